@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// assertionOps are the operators supported by each subject of an assertion,
+// "$" stands for a JSONPath into the response body.
+var assertionOps = map[string][]string{
+	"status": {"==", "!=", "<", "<=", ">", ">=", "in"},
+	"time":   {"<", "<=", ">", ">="},
+	"header": {"exists", "==", "!=", "contains", "matches"},
+	"body":   {"contains", "matches"},
+	"$":      {"exists", "==", "!=", "<", "<=", ">", ">=", "matches"},
+}
+
+// assertion is a check of a response, written like
+//
+//	status == 200
+//	status in 200..299
+//	time < 500
+//	header Content-Type matches ^application/json
+//	body contains "ok"
+//	$.users[0].id == 42
+type assertion struct {
+	subject string
+	header  string
+	path    jsonPath
+	op      string
+	value   string
+}
+
+func parseAssertion(s string) (*assertion, error) {
+	a := &assertion{}
+	rest := strings.TrimSpace(s)
+	if strings.HasPrefix(rest, "$") {
+		path, after, err := parseJSONPathPrefix(rest)
+		if err != nil {
+			return nil, err
+		}
+		a.subject, a.path, rest = "$", path, after
+	} else {
+		a.subject, rest = cutField(rest)
+		if a.subject == "header" {
+			if a.header, rest = cutField(rest); a.header == "" {
+				return nil, errors.New("header needs a name")
+			}
+		}
+	}
+
+	ops, ok := assertionOps[a.subject]
+	if !ok {
+		return nil, fmt.Errorf("unknown subject %q, expected status, time, header, body or a JSONPath", a.subject)
+	}
+
+	a.op, a.value = cutField(rest)
+	switch {
+	case !slices.Contains(ops, a.op):
+		return nil, fmt.Errorf("unknown %s operator %q, expected one of %s", a.subjectName(), a.op, strings.Join(ops, " "))
+	case a.op == "exists" && a.value != "":
+		return nil, fmt.Errorf("unexpected %q after exists", a.value)
+	case a.op != "exists" && a.value == "":
+		return nil, fmt.Errorf("%s needs a value", a.op)
+	case a.op == "matches":
+		if _, err := regexp.Compile(a.value); err != nil {
+			return nil, err
+		}
+	case a.op == "in":
+		if _, _, err := parseRange(a.value); err != nil {
+			return nil, err
+		}
+	case a.subject == "status" || a.subject == "time":
+		if _, err := parseMilliseconds(a.value); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (a *assertion) subjectName() string {
+	if a.subject == "$" {
+		return "jsonpath"
+	}
+
+	return a.subject
+}
+
+// cutField returns the first space separated field of s and the text after
+// it.
+func cutField(s string) (string, string) {
+	field, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return field, strings.TrimSpace(rest)
+}
+
+// parseRange parses an inclusive range like 200..299.
+func parseRange(s string) (int, int, error) {
+	from, to, found := strings.Cut(s, "..")
+	low, err := strconv.Atoi(strings.TrimSpace(from))
+	if !found || err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q, expected like 200..299", s)
+	}
+	high, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil || high < low {
+		return 0, 0, fmt.Errorf("invalid range %q, expected like 200..299", s)
+	}
+
+	return low, high, nil
+}
+
+// parseMilliseconds parses a number, which may have an ms unit.
+func parseMilliseconds(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "ms"))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+
+	return n, nil
+}
+
+// check tests resp, and describes what was found when it fails.
+func (a *assertion) check(resp responseMsg) (bool, string) {
+	switch a.subject {
+	case "status":
+		return compareNumber(resp.statusCode, a.op, a.value), fmt.Sprintf("got %d", resp.statusCode)
+	case "time":
+		return compareNumber(int(resp.responseTime), a.op, a.value), fmt.Sprintf("took %d ms", resp.responseTime)
+	case "header":
+		value, found := lookupHeaderLine(resp.responseHeaders, a.header)
+		if !found {
+			return false, "no such header"
+		}
+		return compareText(value, a.op, a.value), "got " + jsonString(value)
+	case "body":
+		return compareText(resp.responseBody, a.op, a.value), "not in the body"
+	}
+
+	doc, err := decodeJSON(resp.responseBody)
+	if err != nil {
+		return false, err.Error()
+	}
+	results := a.path.eval(doc)
+	if len(results) == 0 {
+		return false, "no value"
+	}
+	if a.op == "exists" {
+		return true, ""
+	}
+
+	actual := results[0]
+	data, err := json.Marshal(actual)
+	if err != nil {
+		return false, err.Error()
+	}
+	if a.op == "matches" {
+		s, ok := actual.(string)
+		if !ok {
+			s = string(data)
+		}
+		return compareText(s, a.op, a.value), "got " + string(data)
+	}
+
+	return compareJSON(actual, jsonLiteral(a.value), a.op), "got " + string(data)
+}
+
+// jsonLiteral decodes a json value, taking anything else as a string.
+func jsonLiteral(s string) any {
+	v, err := decodeJSON(s)
+	if err != nil {
+		return s
+	}
+
+	return v
+}
+
+func compareNumber(n int, op, value string) bool {
+	if op == "in" {
+		low, high, err := parseRange(value)
+		return err == nil && n >= low && n <= high
+	}
+
+	expected, err := parseMilliseconds(value)
+	if err != nil {
+		return false
+	}
+
+	return compareJSON(json.Number(strconv.Itoa(n)), json.Number(strconv.Itoa(expected)), op)
+}
+
+func compareText(s, op, value string) bool {
+	switch op {
+	case "exists":
+		return true
+	case "==":
+		return s == value
+	case "!=":
+		return s != value
+	case "contains":
+		return strings.Contains(s, value)
+	case "matches":
+		re, err := regexp.Compile(value)
+		return err == nil && re.MatchString(s)
+	}
+
+	return false
+}
+
+// testResult is the outcome of an assertion or of a test in a script.
+type testResult struct {
+	name    string
+	passed  bool
+	message string
+}
+
+// runTests checks resp against the assertions in tests.
+func runTests(tests []string, resp responseMsg) []testResult {
+	var results []testResult
+	for _, test := range tests {
+		a, err := parseAssertion(test)
+		if err != nil {
+			results = append(results, testResult{name: test, message: err.Error()})
+			continue
+		}
+
+		passed, found := a.check(resp)
+		results = append(results, testResult{name: test, passed: passed, message: found})
+	}
+
+	return results
+}
+
+// formatTestResults renders the report shown in the Tests tab, followed by
+// the output of the scripts, and returns the number of failed tests.
+func formatTestResults(results []testResult, logs []string) (string, int) {
+	var b strings.Builder
+	failed := 0
+	for _, result := range results {
+		if !result.passed {
+			failed++
+			b.WriteString(errorStyle.Render("✗ "+result.name+": "+result.message) + "\n")
+			continue
+		}
+		b.WriteString(focusedStyle.Render("✓ "+result.name) + "\n")
+	}
+
+	if len(logs) > 0 {
+		if len(results) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("Script output:\n")
+		for _, line := range logs {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), failed
+}
+
+// formatPendingTests lists the tests of a request that has not been sent yet.
+func formatPendingTests(tests []string) string {
+	if len(tests) == 0 {
+		return `No tests for this request. Add assertions to a collection request, like
+
+  "tests": ["status == 200", "header Content-Type matches json", "$.id exists", "time < 500"]
+
+and load it with alt+e, or call test(name, fn) in its post-response script.`
+	}
+
+	var b strings.Builder
+	for _, test := range tests {
+		b.WriteString("· " + test + "\n")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var testResponse = responseMsg{
+	statusCode:      201,
+	responseTime:    120,
+	responseHeaders: "Content-Type: application/json; charset=utf-8\nX-Request-Id: abc",
+	responseBody:    `{"id": 42, "name": "Ada", "tags": ["admin"], "score": 9.5, "active": true, "manager": null}`,
+}
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		s       string
+		wantErr string
+	}{
+		{"status == 200", ""},
+		{"status in 200..299", ""},
+		{"time < 500ms", ""},
+		{"header Content-Type matches ^application/json", ""},
+		{"header X-Request-Id exists", ""},
+		{`body contains "ok"`, ""},
+		{"$.users[0].id == 42", ""},
+		{"$['a b'] exists", ""},
+		{"latency < 5", `unknown subject "latency"`},
+		{"header", "header needs a name"},
+		{"status contains 2", `unknown status operator "contains"`},
+		{"$.id contains 4", `unknown jsonpath operator "contains"`},
+		{"$.id exists now", `unexpected "now" after exists`},
+		{"status ==", "== needs a value"},
+		{"status in 299..200", "invalid range"},
+		{"time < soon", `"soon" is not a number`},
+		{"body matches (", "error parsing regexp"},
+	}
+	for _, tt := range tests {
+		_, err := parseAssertion(tt.s)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("parseAssertion(%q) error = %v", tt.s, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("parseAssertion(%q) error = %v, want %q", tt.s, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAssertionCheck(t *testing.T) {
+	tests := []struct {
+		s           string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{"status == 201", true, "got 201"},
+		{"status != 201", false, "got 201"},
+		{"status in 200..299", true, "got 201"},
+		{"status >= 400", false, "got 201"},
+		{"time < 500", true, "took 120 ms"},
+		{"time > 500ms", false, "took 120 ms"},
+		{"header content-type matches ^application/json", true, `got "application/json; charset=utf-8"`},
+		{"header X-Request-Id == abc", true, `got "abc"`},
+		{"header X-Missing exists", false, "no such header"},
+		{`body contains "Ada"`, true, "not in the body"},
+		{"body matches ^<html", false, "not in the body"},
+		{"$.id == 42", true, "got 42"},
+		{"$.id > 40", true, "got 42"},
+		{"$.score < 9", false, "got 9.5"},
+		{`$.name == "Ada"`, true, `got "Ada"`},
+		{"$.name == Ada", true, `got "Ada"`},
+		{"$.name matches ^A", true, `got "Ada"`},
+		{`$.tags == ["admin"]`, true, `got ["admin"]`},
+		{"$.active == true", true, "got true"},
+		{"$.manager == null", true, "got null"},
+		{"$.manager exists", true, ""},
+		{"$.missing exists", false, "no value"},
+	}
+	for _, tt := range tests {
+		a, err := parseAssertion(tt.s)
+		if err != nil {
+			t.Fatalf("parseAssertion(%q) error = %v", tt.s, err)
+		}
+		if passed, message := a.check(testResponse); passed != tt.wantPassed || message != tt.wantMessage {
+			t.Errorf("check(%q) = %v, %q, want %v, %q", tt.s, passed, message, tt.wantPassed, tt.wantMessage)
+		}
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	results := runTests([]string{"status == 201", "$.id == 7", "status is ok"}, testResponse)
+	if len(results) != 3 {
+		t.Fatalf("runTests() = %d results, want 3", len(results))
+	}
+	if !results[0].passed || results[1].passed || results[1].message != "got 42" || results[2].passed {
+		t.Errorf("runTests() = %+v", results)
+	}
+
+	report, failed := formatTestResults(results, []string{"done"})
+	if failed != 2 || !strings.Contains(report, "✓ status == 201") || !strings.Contains(report, "✗ $.id == 7: got 42") || !strings.HasSuffix(report, "Script output:\n  done") {
+		t.Errorf("formatTestResults() = %q, %d", report, failed)
+	}
+}
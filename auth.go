@@ -0,0 +1,527 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AuthNone   = "none"
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apikey"
+	AuthDigest = "digest"
+	AuthOAuth2 = "oauth2"
+	AuthAWSv4  = "awsv4"
+
+	grantClientCredentials = "client_credentials"
+	grantPassword          = "password"
+
+	// tokenExpiryMargin renews OAuth2 tokens shortly before they expire
+	tokenExpiryMargin = 30 * time.Second
+)
+
+// authKeys are the names used in the auth editor for each auth type, in
+// display order.
+var authKeys = map[string][]string{
+	AuthNone:   nil,
+	AuthBasic:  {"username", "password"},
+	AuthBearer: {"token"},
+	AuthAPIKey: {"key", "value", "in"},
+	AuthDigest: {"username", "password"},
+	AuthOAuth2: {"grant", "tokenUrl", "clientId", "clientSecret", "scope", "username", "password"},
+	AuthAWSv4:  {"accessKey", "secretKey", "sessionToken", "region", "service"},
+}
+
+var authTypes = []string{AuthNone, AuthBasic, AuthBearer, AuthAPIKey, AuthDigest, AuthOAuth2, AuthAWSv4}
+
+// Auth is applied to a request when it is sent. Requests without auth inherit
+// the auth of their folder or collection, type none turns it off.
+type Auth struct {
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Value    string `json:"value,omitempty"`
+	// In is where an API key is sent, header or query
+	In           string `json:"in,omitempty"`
+	Grant        string `json:"grant,omitempty"`
+	TokenURL     string `json:"tokenUrl,omitempty"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// AccessKey, SecretKey and SessionToken default to the AWS_* environment
+	// variables
+	AccessKey    string `json:"accessKey,omitempty"`
+	SecretKey    string `json:"secretKey,omitempty"`
+	SessionToken string `json:"sessionToken,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Service      string `json:"service,omitempty"`
+}
+
+func (a *Auth) field(key string) *string {
+	switch key {
+	case "type":
+		return &a.Type
+	case "username":
+		return &a.Username
+	case "password":
+		return &a.Password
+	case "token":
+		return &a.Token
+	case "key":
+		return &a.Key
+	case "value":
+		return &a.Value
+	case "in":
+		return &a.In
+	case "grant":
+		return &a.Grant
+	case "tokenUrl":
+		return &a.TokenURL
+	case "clientId":
+		return &a.ClientID
+	case "clientSecret":
+		return &a.ClientSecret
+	case "scope":
+		return &a.Scope
+	case "accessKey":
+		return &a.AccessKey
+	case "secretKey":
+		return &a.SecretKey
+	case "sessionToken":
+		return &a.SessionToken
+	case "region":
+		return &a.Region
+	case "service":
+		return &a.Service
+	}
+
+	return nil
+}
+
+func (a *Auth) Validate() error {
+	keys, ok := authKeys[a.Type]
+	if !ok {
+		return fmt.Errorf("unknown auth type %q, expected one of %s", a.Type, strings.Join(authTypes, ", "))
+	}
+
+	required := func(key string) error {
+		if *a.field(key) == "" {
+			return fmt.Errorf("%s auth requires %s", a.Type, key)
+		}
+		return nil
+	}
+
+	// Values for keys of other types would be silently ignored
+	for _, typeKeys := range authKeys {
+		for _, key := range typeKeys {
+			if *a.field(key) != "" && !slices.Contains(keys, key) {
+				return fmt.Errorf("%s auth does not use %s", a.Type, key)
+			}
+		}
+	}
+
+	switch a.Type {
+	case AuthBasic, AuthDigest:
+		return required("username")
+	case AuthBearer:
+		return required("token")
+	case AuthAPIKey:
+		if a.In != "" && a.In != "header" && a.In != "query" {
+			return fmt.Errorf("invalid api key location %q, expected header or query", a.In)
+		}
+		return required("key")
+	case AuthOAuth2:
+		switch a.Grant {
+		case grantClientCredentials:
+		case grantPassword:
+			if err := required("username"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid oauth2 grant %q, expected %s or %s", a.Grant, grantClientCredentials, grantPassword)
+		}
+		if err := required("clientId"); err != nil {
+			return err
+		}
+		if err := required("tokenUrl"); err != nil {
+			return err
+		}
+		if !strings.Contains(a.TokenURL, "{{") {
+			if u, err := url.Parse(a.TokenURL); err != nil || u.Host == "" {
+				return fmt.Errorf("invalid token url %q", a.TokenURL)
+			}
+		}
+	case AuthAWSv4:
+		if err := required("region"); err != nil {
+			return err
+		}
+		return required("service")
+	}
+
+	return nil
+}
+
+// substitute returns a copy of a with f applied to all of its values.
+func (a *Auth) substitute(f func(string) string) *Auth {
+	if a == nil {
+		return nil
+	}
+
+	resolved := *a
+	for _, key := range authKeys[a.Type] {
+		field := resolved.field(key)
+		*field = f(*field)
+	}
+
+	return &resolved
+}
+
+// apply adds the auth that is a plain header or query parameter to req. Digest
+// and OAuth2 need a round trip first and AWS signatures the time of sending,
+// they are handled by doRequest.
+func (a *Auth) apply(req *http.Request) {
+	switch a.Type {
+	case AuthBasic:
+		req.SetBasicAuth(a.Username, a.Password)
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case AuthAPIKey:
+		if a.In == "query" {
+			query := req.URL.Query()
+			query.Set(a.Key, a.Value)
+			req.URL.RawQuery = query.Encode()
+			return
+		}
+		req.Header.Set(a.Key, a.Value)
+	}
+}
+
+// formatAuth renders the auth editor for the auth of the current request and
+// the auth it inherits when it has none.
+func formatAuth(auth, inherited *Auth, inheritedFrom string) string {
+	var b strings.Builder
+	b.WriteString("# Auth of the current request, an empty type inherits\n")
+	fmt.Fprintf(&b, "# Types: %s\n", strings.Join(authTypes, ", "))
+	if inherited != nil {
+		fmt.Fprintf(&b, "# Without a type it inherits %s auth from %s\n", inherited.Type, inheritedFrom)
+	}
+
+	if auth == nil {
+		b.WriteString("type=")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "type=%s", auth.Type)
+	for _, key := range authKeys[auth.Type] {
+		fmt.Fprintf(&b, "\n%s=%s", key, *auth.field(key))
+	}
+
+	return b.String()
+}
+
+// parseAuth reads the auth editor, returning nil when the request inherits its
+// auth.
+func parseAuth(text string) (*Auth, error) {
+	var auth Auth
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key=value", i+1)
+		}
+		field := auth.field(strings.TrimSpace(key))
+		if field == nil {
+			return nil, fmt.Errorf("line %d: unknown key %q", i+1, strings.TrimSpace(key))
+		}
+		*field = strings.TrimSpace(value)
+	}
+
+	if auth == (Auth{}) {
+		return nil, nil
+	}
+	if auth.Type == "" {
+		return nil, errors.New("type is required")
+	}
+
+	return &auth, auth.Validate()
+}
+
+// authFor returns the auth inherited by the request at the json key path of
+// the rendered collection, and a description of where it is set.
+func (c *Collection) authFor(keyPath []any) (*Auth, string) {
+	if c == nil {
+		return nil, ""
+	}
+
+	auth, from := c.Auth, fmt.Sprintf("collection %q", c.Name)
+	folders := c.Folders
+	for i := 0; i+1 < len(keyPath); i += 2 {
+		index, ok := keyPath[i+1].(int)
+		if !ok || keyPath[i] != "folders" || index >= len(folders) {
+			break
+		}
+
+		if f := folders[index]; f.Auth != nil {
+			auth, from = f.Auth, fmt.Sprintf("folder %q", f.Name)
+		}
+		folders = folders[index].Folders
+	}
+
+	return auth, from
+}
+
+// digestTransport answers Digest challenges by resending the request with
+// credentials.
+type digestTransport struct {
+	base               http.RoundTripper
+	username, password string
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	challenge := digestChallenge(res.Header.Values("WWW-Authenticate"))
+	if challenge == nil || (req.Body != nil && req.GetBody == nil) {
+		return res, nil
+	}
+
+	authorization, err := digestAuthorization(challenge, req, t.username, t.password)
+	if err != nil {
+		return res, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", authorization)
+
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	return t.base.RoundTrip(retry)
+}
+
+// digestChallenge returns the parameters of the first Digest challenge.
+func digestChallenge(headers []string) map[string]string {
+	for _, header := range headers {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		params := map[string]string{}
+		for rest = strings.TrimSpace(rest); rest != ""; {
+			key, value, found := strings.Cut(rest, "=")
+			if !found {
+				break
+			}
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.TrimLeft(value, " ")
+
+			if strings.HasPrefix(value, `"`) {
+				end := strings.Index(value[1:], `"`)
+				if end < 0 {
+					break
+				}
+				params[key], rest = value[1:end+1], value[end+2:]
+			} else {
+				params[key], rest, _ = strings.Cut(value, ",")
+				params[key] = strings.TrimSpace(params[key])
+			}
+			rest = strings.TrimLeft(rest, ", ")
+		}
+
+		return params
+	}
+
+	return nil
+}
+
+// digestAuthorization computes the Authorization header for a Digest
+// challenge as described in RFC 7616.
+func digestAuthorization(challenge map[string]string, req *http.Request, username, password string) (string, error) {
+	algorithm := challenge["algorithm"]
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	realm, nonce, uri := challenge["realm"], challenge["nonce"], req.URL.RequestURI()
+	cnonce := rand.Text()
+	ha1 := h(username + ":" + realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(req.Method + ":" + uri)
+
+	params := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+	}
+
+	qops := strings.Split(challenge["qop"], ",")
+	if slices.ContainsFunc(qops, func(qop string) bool { return strings.TrimSpace(qop) == "auth" }) {
+		const nc = "00000001"
+		response := h(strings.Join([]string{ha1, nonce, nc, cnonce, "auth", ha2}, ":"))
+		params = append(params, "qop=auth", "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce), fmt.Sprintf("response=%q", response))
+	} else {
+		params = append(params, fmt.Sprintf("response=%q", h(ha1+":"+nonce+":"+ha2)))
+	}
+	if algorithm != "" {
+		params = append(params, "algorithm="+algorithm)
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		params = append(params, fmt.Sprintf("opaque=%q", opaque))
+	}
+
+	return "Digest " + strings.Join(params, ", "), nil
+}
+
+type oauthToken struct {
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+// tokenCache keeps the OAuth2 access tokens of the session, so tokens are
+// only fetched again once they expire.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*oauthToken
+}
+
+// token returns a valid access token for auth, refreshing or fetching a new
+// one with client when needed.
+func (c *tokenCache) token(client *http.Client, req *http.Request, auth *Auth) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join([]string{auth.TokenURL, auth.Grant, auth.ClientID, auth.Username, auth.Scope}, "\n")
+	cached := c.tokens[key]
+	if cached != nil && (cached.expiry.IsZero() || time.Now().Add(tokenExpiryMargin).Before(cached.expiry)) {
+		return cached.accessToken, nil
+	}
+
+	var token *oauthToken
+	var err error
+	if cached != nil && cached.refreshToken != "" {
+		token, err = fetchToken(client, req, auth, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cached.refreshToken},
+		})
+	}
+	// A rejected refresh token falls back to the grant itself
+	if token == nil {
+		form := url.Values{"grant_type": {auth.Grant}}
+		if auth.Grant == grantPassword {
+			form.Set("username", auth.Username)
+			form.Set("password", auth.Password)
+		}
+		if auth.Scope != "" {
+			form.Set("scope", auth.Scope)
+		}
+		token, err = fetchToken(client, req, auth, form)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if c.tokens == nil {
+		c.tokens = map[string]*oauthToken{}
+	}
+	if token.refreshToken == "" && cached != nil {
+		token.refreshToken = cached.refreshToken
+	}
+	c.tokens[key] = token
+
+	return token.accessToken, nil
+}
+
+// fetchToken posts form to the token url of auth, with the context of req.
+func fetchToken(client *http.Client, req *http.Request, auth *Auth, form url.Values) (*oauthToken, error) {
+	if auth.ClientSecret == "" {
+		form.Set("client_id", auth.ClientID)
+	}
+
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+	if auth.ClientSecret != "" {
+		tokenReq.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+	}
+
+	res, err := client.Do(tokenReq)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token request: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode < 300 {
+		return nil, fmt.Errorf("oauth2 token response: %w", err)
+	}
+	switch {
+	case body.Error != "":
+		return nil, fmt.Errorf("oauth2 token request: %s", strings.TrimSpace(body.Error+" "+body.ErrorDescription))
+	case res.StatusCode >= 300:
+		return nil, fmt.Errorf("oauth2 token request: %s", res.Status)
+	case body.AccessToken == "":
+		return nil, errors.New("oauth2 token response has no access_token")
+	}
+
+	token := &oauthToken{accessToken: body.AccessToken, refreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
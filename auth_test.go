@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDigestChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    map[string]string
+	}{
+		{
+			name:    "quoted and bare values",
+			headers: []string{`Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", algorithm=MD5, opaque="5ccc069c403ebaf9f0171e9517f40e41"`},
+			want: map[string]string{
+				"realm": "testrealm@host.com", "qop": "auth,auth-int", "nonce": "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+				"algorithm": "MD5", "opaque": "5ccc069c403ebaf9f0171e9517f40e41",
+			},
+		},
+		{
+			name:    "first digest challenge",
+			headers: []string{`Basic realm="a"`, `digest Realm="b", nonce=1`},
+			want:    map[string]string{"realm": "b", "nonce": "1"},
+		},
+		{
+			name:    "comma in a quoted value",
+			headers: []string{`Digest realm="a, b", nonce="n"`},
+			want:    map[string]string{"realm": "a, b", "nonce": "n"},
+		},
+		{
+			name:    "no digest challenge",
+			headers: []string{`Bearer realm="a"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := digestChallenge(tt.headers); !maps.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("digestChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// digestParams parses the parameters of an Authorization header.
+func digestParams(t *testing.T, authorization string) map[string]string {
+	t.Helper()
+	params := digestChallenge([]string{authorization})
+	if params == nil {
+		t.Fatalf("authorization %q is not a digest", authorization)
+	}
+
+	return params
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigestAuthorization(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://www.nowhere.org/dir/index.html", nil)
+
+	// The example of RFC 2069, without qop
+	challenge := map[string]string{"realm": "testrealm@host.com", "nonce": "dcd98b7102dd2f0e8b11d0f600bfb0c093", "opaque": "5ccc069c403ebaf9f0171e9517f40e41"}
+	authorization, err := digestAuthorization(challenge, req, "Mufasa", "CircleOfLife")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := digestParams(t, authorization)
+	if params["response"] != "1949323746fe6a43ef61f9606e7febea" {
+		t.Errorf("response = %q, want the RFC 2069 response", params["response"])
+	}
+	if params["uri"] != "/dir/index.html" || params["opaque"] != challenge["opaque"] || params["username"] != "Mufasa" {
+		t.Errorf("authorization = %q", authorization)
+	}
+
+	// The example of RFC 2617, with qop=auth and a client nonce
+	challenge["qop"] = "auth,auth-int"
+	authorization, err = digestAuthorization(challenge, req, "Mufasa", "Circle Of Life")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params = digestParams(t, authorization)
+	ha1 := md5Hex("Mufasa:testrealm@host.com:Circle Of Life")
+	ha2 := md5Hex("GET:/dir/index.html")
+	want := md5Hex(ha1 + ":" + challenge["nonce"] + ":00000001:" + params["cnonce"] + ":auth:" + ha2)
+	if params["qop"] != "auth" || params["nc"] != "00000001" || params["cnonce"] == "" || params["response"] != want {
+		t.Errorf("authorization = %q, want response %q", authorization, want)
+	}
+
+	if _, err := digestAuthorization(map[string]string{"algorithm": "SHA-512-256"}, req, "a", "b"); err == nil {
+		t.Error("digestAuthorization() accepted an unsupported algorithm")
+	}
+}
+
+func TestDigestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "Digest ") {
+			w.Header().Set("WWW-Authenticate", `Digest realm="api", nonce="abc", qop="auth", algorithm=SHA-256`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := digestChallenge([]string{authorization})
+		body, _ := io.ReadAll(r.Body)
+		if params["username"] != "user" || params["algorithm"] != "SHA-256" || params["uri"] != "/items?id=1" || string(body) != "body" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &digestTransport{base: http.DefaultTransport, username: "user", password: "secret"}}
+	res, err := client.Post(server.URL+"/items?id=1", "text/plain", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestAuthFor(t *testing.T) {
+	c := &Collection{
+		Name: "api",
+		Auth: &Auth{Type: AuthBearer, Token: "collection"},
+		Folders: []*Folder{
+			{Name: "public", Auth: &Auth{Type: AuthNone}, Requests: []*Request{{Method: "GET", URL: "/a"}}},
+			{Name: "users", Folders: []*Folder{
+				{Name: "admin", Auth: &Auth{Type: AuthBasic, Username: "admin"}, Requests: []*Request{{Method: "GET", URL: "/b"}}},
+			}},
+		},
+		Requests: []*Request{{Method: "GET", URL: "/c"}},
+	}
+
+	tests := []struct {
+		method, url string
+		wantType    string
+		wantFrom    string
+	}{
+		{"GET", "/a", AuthNone, `folder "public"`},
+		{"GET", "/b", AuthBasic, `folder "admin"`},
+		{"GET", "/c", AuthBearer, `collection "api"`},
+	}
+	for _, tt := range tests {
+		path := c.requestPath(tt.method, tt.url)
+		if c.requestAt(path) == nil {
+			t.Fatalf("requestPath(%s %s) = %v, not a request", tt.method, tt.url, path)
+		}
+		auth, from := c.authFor(path)
+		if auth.Type != tt.wantType || from != tt.wantFrom {
+			t.Errorf("authFor(%v) = %s from %s, want %s from %s", path, auth.Type, from, tt.wantType, tt.wantFrom)
+		}
+	}
+
+	if path := c.requestPath("POST", "/a"); path != nil {
+		t.Errorf("requestPath() of a missing request = %v", path)
+	}
+}
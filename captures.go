@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// capture sets a variable from a response, written like
+//
+//	token = $.access_token
+//	location = header Location
+type capture struct {
+	name   string
+	path   jsonPath
+	header string
+}
+
+func parseCapture(s string) (*capture, error) {
+	name, source, found := strings.Cut(s, "=")
+	name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+	if !found || !variableNamePattern.MatchString(name) {
+		return nil, errors.New("expected NAME = $.path or NAME = header Name")
+	}
+
+	c := &capture{name: name}
+	if strings.HasPrefix(source, "$") {
+		path, err := parseJSONPath(source)
+		if err != nil {
+			return nil, err
+		}
+		c.path = path
+		return c, nil
+	}
+
+	field, header := cutField(source)
+	if field != "header" || header == "" {
+		return nil, fmt.Errorf("capture %s from a $.path or a header Name", name)
+	}
+	c.header = header
+
+	return c, nil
+}
+
+// value returns the captured value, strings as they are and other json values
+// as json text.
+func (c *capture) value(resp responseMsg) (string, error) {
+	if c.header != "" {
+		value, found := lookupHeaderLine(resp.responseHeaders, c.header)
+		if !found {
+			return "", fmt.Errorf("no %s header", c.header)
+		}
+		return value, nil
+	}
+
+	doc, err := decodeJSON(resp.responseBody)
+	if err != nil {
+		return "", err
+	}
+	results := c.path.eval(doc)
+	if len(results) == 0 {
+		return "", errors.New("no value in the body")
+	}
+	if s, ok := results[0].(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(results[0])
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// runCaptures returns the variables captured from resp, and an error naming
+// the captures that found nothing.
+func runCaptures(captures []string, resp responseMsg) (map[string]string, error) {
+	variables := map[string]string{}
+	var failed []string
+	for _, s := range captures {
+		c, err := parseCapture(s)
+		if err == nil {
+			var value string
+			if value, err = c.value(resp); err == nil {
+				variables[c.name] = value
+				continue
+			}
+		}
+		failed = append(failed, fmt.Sprintf("%q: %s", s, err))
+	}
+
+	if len(failed) > 0 {
+		return variables, errors.New("capture " + strings.Join(failed, "; "))
+	}
+
+	return variables, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseCapture(t *testing.T) {
+	tests := []struct {
+		s          string
+		wantName   string
+		wantHeader string
+		wantErr    bool
+	}{
+		{"token = $.access_token", "token", "", false},
+		{" id=$.users[0].id ", "id", "", false},
+		{"location = header Location", "location", "Location", false},
+		{"token $.access_token", "", "", true},
+		{"a b = $.id", "", "", true},
+		{"token = $.", "", "", true},
+		{"token = header", "", "", true},
+		{"token = body", "", "", true},
+	}
+	for _, tt := range tests {
+		c, err := parseCapture(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCapture(%q) error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (c.name != tt.wantName || c.header != tt.wantHeader) {
+			t.Errorf("parseCapture(%q) = %q %q, want %q %q", tt.s, c.name, c.header, tt.wantName, tt.wantHeader)
+		}
+	}
+}
+
+func TestRunCaptures(t *testing.T) {
+	variables, err := runCaptures([]string{
+		"id = $.id",
+		"name = $.name",
+		"tags = $.tags",
+		"requestId = header x-request-id",
+		"missing = $.missing",
+		"etag = header ETag",
+	}, testResponse)
+
+	want := map[string]string{"id": "42", "name": "Ada", "tags": `["admin"]`, "requestId": "abc"}
+	if !maps.Equal(variables, want) {
+		t.Errorf("runCaptures() = %v, want %v", variables, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), `"missing = $.missing": no value in the body`) || !strings.Contains(err.Error(), `"etag = header ETag": no ETag header`) {
+		t.Errorf("runCaptures() error = %v, want the two failed captures", err)
+	}
+
+	if _, err := runCaptures([]string{"id = $.id"}, responseMsg{responseBody: "<html>"}); err == nil {
+		t.Errorf("runCaptures() of a body that is not json succeeded")
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"time"
 
@@ -12,36 +14,174 @@ import (
 )
 
 type responseMsg struct {
+	// requestID identifies the run, so responses of cancelled or superseded
+	// requests can be ignored
+	requestID       int
 	responseBody    string
 	responseHeaders string
 	responseTime    int64
 	statusCode      int
+	timeline        string
 }
 
 type errMsg struct {
 	err error
+	// requestID is set for errors of a sent request
+	requestID int
 }
 
-func doRequest(url string, method string, headers map[string]string, requestBody string) tea.Cmd {
-	return func() tea.Msg {
-		c := &http.Client{Timeout: 10 * time.Second}
+// preRequestMsg carries the request changed by the pre-request script.
+type preRequestMsg struct {
+	requestID int
+	draft     requestDraft
+	result    *scriptResult
+}
+
+// postResponseMsg carries the test results of a response, those of the
+// post-response script included.
+type postResponseMsg struct {
+	requestID int
+	tests     []testResult
+	logs      []string
+	variables map[string]string
+	err       error
+}
 
-		req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(requestBody)))
+// filterMsg carries the response body filtered by a jq or JSONPath
+// expression.
+type filterMsg struct {
+	filterID int
+	body     string
+	err      error
+}
+
+// highlightMsg carries a response body pretty-printed and highlighted.
+type highlightMsg struct {
+	highlightID int
+	// source and format are what was highlighted
+	source string
+	format string
+	body   string
+}
 
-		for key, value := range headers {
-			req.Header.Add(key, value)
+// requestDraft is the request as typed in the editors, before variable
+// substitution.
+type requestDraft struct {
+	url     string
+	method  string
+	headers map[string]string
+	body    string
+}
+
+// requestSpec is the request as entered in the editors, after variable
+// substitution. Secret variables are still {{placeholders}}, their values are
+// carried separately until the request is sent.
+type requestSpec struct {
+	url      string
+	method   string
+	headers  map[string]string
+	body     string
+	secrets  map[string]string
+	settings Settings
+	auth     *Auth
+}
+
+func (s requestSpec) withSecrets() requestSpec {
+	if len(s.secrets) == 0 {
+		return s
+	}
+
+	lookup := func(name string) (string, bool) {
+		value, ok := s.secrets[name]
+		return value, ok
+	}
+	substitute := func(v string) string {
+		v, _ = substituteVariables(v, lookup)
+		return v
+	}
+
+	resolved := requestSpec{
+		url:      substitute(s.url),
+		method:   substitute(s.method),
+		headers:  map[string]string{},
+		body:     substitute(s.body),
+		settings: s.settings,
+		auth:     s.auth.substitute(substitute),
+	}
+	for key, value := range s.headers {
+		resolved.headers[substitute(key)] = substitute(value)
+	}
+
+	return resolved
+}
+
+// buildRequest creates the request postui sends, shared by doRequest and the
+// generated code snippets.
+func buildRequest(spec requestSpec) (*http.Request, error) {
+	req, err := http.NewRequest(spec.method, spec.url, bytes.NewBuffer([]byte(spec.body)))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range spec.headers {
+		req.Header.Add(key, value)
+	}
+	if spec.auth != nil {
+		spec.auth.apply(req)
+	}
+
+	return req, nil
+}
+
+func doRequest(ctx context.Context, requestID int, spec requestSpec, jar http.CookieJar, tokens *tokenCache, transports *transportCache) tea.Cmd {
+	return func() tea.Msg {
+		c, err := newClient(spec.settings, transports)
+		if err != nil {
+			return errMsg{err: err, requestID: requestID}
 		}
+		c.Jar = jar
 
+		// Secrets are only filled in here, so they never reach the editors
+		spec = spec.withSecrets()
+		req, err := buildRequest(spec)
 		if err != nil {
-			return errMsg{err}
+			return errMsg{err: err, requestID: requestID}
+		}
+		req = req.WithContext(ctx)
+
+		switch {
+		case spec.auth == nil:
+		case spec.auth.Type == AuthDigest:
+			c.Transport = &digestTransport{base: c.Transport, username: spec.auth.Username, password: spec.auth.Password}
+		case spec.auth.Type == AuthOAuth2:
+			token, err := tokens.token(c, req, spec.auth)
+			if err != nil {
+				return errMsg{err: err, requestID: requestID}
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		case spec.auth.Type == AuthAWSv4:
+			if err := signAWSv4(req, spec.auth, time.Now()); err != nil {
+				return errMsg{err: err, requestID: requestID}
+			}
+		}
+
+		tl := newTimeline(req)
+		checkRedirect := c.CheckRedirect
+		c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			// Only record the hops the client goes on to send
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+			tl.redirect(req)
+			return nil
 		}
 
 		start := time.Now()
-		res, err := c.Do(req)
+		res, err := c.Do(req.WithContext(httptrace.WithClientTrace(ctx, tl.trace())))
 		stop := time.Now()
 		responseTime := stop.Sub(start)
 		if err != nil {
-			return errMsg{err}
+			return errMsg{err: err, requestID: requestID}
 		}
 
 		defer func() {
@@ -50,8 +190,9 @@ func doRequest(url string, method string, headers map[string]string, requestBody
 
 		body, err := io.ReadAll(res.Body)
 		if err != nil {
-			return errMsg{err}
+			return errMsg{err: err, requestID: requestID}
 		}
+		tl.finish(res, stop, time.Now())
 
 		headers := ""
 		for header, values := range res.Header {
@@ -59,11 +200,68 @@ func doRequest(url string, method string, headers map[string]string, requestBody
 		}
 
 		return responseMsg{
+			requestID:       requestID,
 			responseBody:    string(body),
 			responseHeaders: headers,
 			responseTime:    responseTime.Milliseconds(),
 			statusCode:      res.StatusCode,
+			timeline:        tl.String(),
+		}
+	}
+}
+
+// runPreRequest runs the pre-request script on draft, lookup must not read
+// the state of the model since it runs off the ui loop.
+func runPreRequest(ctx context.Context, requestID int, script string, draft requestDraft, lookup func(string) (string, bool)) tea.Cmd {
+	return func() tea.Msg {
+		result, err := runPreRequestScript(ctx, script, &draft, lookup)
+		if err != nil {
+			return errMsg{err: err, requestID: requestID}
+		}
+
+		return preRequestMsg{requestID: requestID, draft: draft, result: result}
+	}
+}
+
+// runPostResponse runs the post-response script on the response and adds its
+// tests and logs to those already checked.
+func runPostResponse(requestID int, script string, sent *HistoryEntry, resp responseMsg, lookup func(string) (string, bool), tests []testResult, logs []string) tea.Cmd {
+	return func() tea.Msg {
+		msg := postResponseMsg{requestID: requestID, tests: tests, logs: logs}
+		result, err := runPostResponseScript(context.Background(), script, sent, resp, lookup)
+		if result != nil {
+			msg.tests = append(msg.tests, result.tests...)
+			msg.logs = append(msg.logs, result.logs...)
+			if err == nil {
+				msg.variables = result.variables
+			}
+		}
+		msg.err = err
+
+		return msg
+	}
+}
+
+// applyFilter filters body off the ui loop, since a jq program can run for
+// long.
+func applyFilter(ctx context.Context, filterID int, expr, body string) tea.Cmd {
+	return func() tea.Msg {
+		filtered, err := filterBody(ctx, expr, body)
+		return filterMsg{filterID: filterID, body: filtered, err: err}
+	}
+}
+
+// highlightResponse formats body off the ui loop, which large bodies would
+// block.
+func highlightResponse(highlightID int, body, format string) tea.Cmd {
+	return func() tea.Msg {
+		msg := highlightMsg{highlightID: highlightID, source: body, format: format}
+		if pretty, err := prettyBody(body, format); err == nil {
+			body = pretty
 		}
+		msg.body = highlightBody(body, format)
+
+		return msg
 	}
 }
 
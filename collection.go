@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	methodPattern   = regexp.MustCompile(`^[A-Za-z]+$`)
+	formBodyPattern = regexp.MustCompile(`^[^=&\s]+=[^&\s]*(&[^=&\s]+=[^&\s]*)*$`)
+
+	templatePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+	// templateUnescaper restores {{variable}} placeholders after url encoding
+	templateUnescaper = strings.NewReplacer("%7B%7B", "{{", "%7D%7D", "}}")
+)
+
+type Collection struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Variables   map[string]string `json:"variables,omitempty"`
+	Auth        *Auth             `json:"auth,omitempty"`
+	Folders     []*Folder         `json:"folders,omitempty"`
+	Requests    []*Request        `json:"requests,omitempty"`
+}
+
+type Folder struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Auth        *Auth      `json:"auth,omitempty"`
+	Folders     []*Folder  `json:"folders,omitempty"`
+	Requests    []*Request `json:"requests,omitempty"`
+}
+
+type Request struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Query       url.Values        `json:"query,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	Settings    *Settings         `json:"settings,omitempty"`
+	Auth        *Auth             `json:"auth,omitempty"`
+	Tests       []string          `json:"tests,omitempty"`
+	Captures    []string          `json:"captures,omitempty"`
+	Scripts     *Scripts          `json:"scripts,omitempty"`
+}
+
+func parseCollection(data []byte) (*Collection, error) {
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (c *Collection) Validate() error {
+	var errs []error
+	if c.Auth != nil {
+		if err := c.Auth.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("auth: %w", err))
+		}
+	}
+
+	return errors.Join(append(errs, validateRequests("", c.Folders, c.Requests)...)...)
+}
+
+func validateRequests(prefix string, folders []*Folder, requests []*Request) []error {
+	var errs []error
+	for i, f := range folders {
+		folderPrefix := fmt.Sprintf("%sfolders[%d]", prefix, i)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("%s: folder is empty", folderPrefix))
+			continue
+		}
+		if strings.TrimSpace(f.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s: folder name is required", folderPrefix))
+		}
+		if f.Auth != nil {
+			if err := f.Auth.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: auth: %w", folderPrefix, err))
+			}
+		}
+		errs = append(errs, validateRequests(folderPrefix+".", f.Folders, f.Requests)...)
+	}
+
+	for i, r := range requests {
+		requestPrefix := fmt.Sprintf("%srequests[%d]", prefix, i)
+		if r == nil {
+			errs = append(errs, fmt.Errorf("%s: request is empty", requestPrefix))
+			continue
+		}
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", requestPrefix, err))
+		}
+	}
+
+	return errs
+}
+
+func (r *Request) Validate() error {
+	if !methodPattern.MatchString(r.Method) {
+		return fmt.Errorf("invalid method %q", r.Method)
+	}
+
+	if strings.TrimSpace(r.URL) == "" {
+		return errors.New("url is required")
+	}
+
+	if r.Settings != nil {
+		if err := r.Settings.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if r.Auth != nil {
+		if err := r.Auth.Validate(); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	for i, test := range r.Tests {
+		if _, err := parseAssertion(test); err != nil {
+			return fmt.Errorf("tests[%d]: %w", i, err)
+		}
+	}
+
+	for i, capture := range r.Captures {
+		if _, err := parseCapture(capture); err != nil {
+			return fmt.Errorf("captures[%d]: %w", i, err)
+		}
+	}
+
+	if r.Scripts != nil {
+		if err := r.Scripts.Validate(); err != nil {
+			return fmt.Errorf("scripts: %w", err)
+		}
+	}
+
+	// Templated urls like {{baseUrl}}/users can only be checked once resolved
+	if strings.Contains(r.URL, "{{") {
+		return nil
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("url %q must be absolute", r.URL)
+	}
+
+	return nil
+}
+
+// FullURL returns the request url including its query parameters.
+func (r *Request) FullURL() string {
+	if len(r.Query) == 0 {
+		return r.URL
+	}
+
+	separator := "?"
+	if strings.Contains(r.URL, "?") {
+		separator = "&"
+	}
+
+	return r.URL + separator + templateUnescaper.Replace(r.Query.Encode())
+}
+
+// requestAt returns the request that the json key path of the rendered
+// collection points into.
+func (c *Collection) requestAt(keyPath []any) *Request {
+	folders, requests := c.Folders, c.Requests
+	for i := 0; i+1 < len(keyPath); i += 2 {
+		index, ok := keyPath[i+1].(int)
+		if !ok {
+			return nil
+		}
+
+		switch keyPath[i] {
+		case "folders":
+			if index >= len(folders) {
+				return nil
+			}
+			folders, requests = folders[index].Folders, folders[index].Requests
+		case "requests":
+			if index >= len(requests) {
+				return nil
+			}
+			return requests[index]
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// findRequest returns the request with the same method and url anywhere in
+// the collection.
+func (c *Collection) findRequest(method, requestUrl string) *Request {
+	for _, r := range c.allRequests() {
+		if r.Method == method && r.URL == requestUrl {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// requestPath returns the json key path of the request findRequest returns,
+// or nil.
+func (c *Collection) requestPath(method, requestUrl string) []any {
+	var find func(folders []*Folder, requests []*Request) []any
+	find = func(folders []*Folder, requests []*Request) []any {
+		for i, r := range requests {
+			if r.Method == method && r.URL == requestUrl {
+				return []any{"requests", i}
+			}
+		}
+		for i, f := range folders {
+			if path := find(f.Folders, f.Requests); path != nil {
+				return append([]any{"folders", i}, path...)
+			}
+		}
+
+		return nil
+	}
+
+	return find(c.Folders, c.Requests)
+}
+
+// allRequests returns the requests of the collection and all of its folders.
+func (c *Collection) allRequests() []*Request {
+	var collect func(folders []*Folder, requests []*Request) []*Request
+	collect = func(folders []*Folder, requests []*Request) []*Request {
+		all := slices.Clone(requests)
+		for _, f := range folders {
+			all = append(all, collect(f.Folders, f.Requests)...)
+		}
+
+		return all
+	}
+
+	return collect(c.Folders, c.Requests)
+}
+
+func newRequest(method, rawUrl string, headers map[string]string, body string) (*Request, error) {
+	// Split the query off by hand, as url.String would escape the braces of
+	// a templated url like {{baseUrl}}/users
+	rawUrl, _, _ = strings.Cut(rawUrl, "#")
+	baseUrl, rawQuery, _ := strings.Cut(rawUrl, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(query) == 0 {
+		query = nil
+	}
+	if len(headers) == 0 {
+		headers = nil
+	}
+
+	path := baseUrl
+	if parsedUrl, err := url.Parse(baseUrl); err == nil && parsedUrl.Host != "" {
+		path = parsedUrl.Path
+	}
+
+	r := &Request{
+		Name:        fmt.Sprintf("%s %s", method, path),
+		Method:      method,
+		URL:         baseUrl,
+		Query:       query,
+		Headers:     headers,
+		ContentType: headerValue(headers, "Content-Type"),
+		Body:        body,
+	}
+
+	return r, r.Validate()
+}
+
+// update overwrites what is sent by r with the request from the editor, while
+// keeping the name and description given in the collection.
+func (r *Request) update(from *Request) {
+	r.Query = from.Query
+	r.Headers = from.Headers
+	r.ContentType = from.ContentType
+	r.Body = from.Body
+	r.Settings = from.Settings
+	r.Auth = from.Auth
+	r.Tests = from.Tests
+	r.Captures = from.Captures
+	r.Scripts = from.Scripts
+}
+
+func (r *Request) setHeader(key, value string) {
+	if r.Headers == nil {
+		r.Headers = map[string]string{}
+	}
+	r.Headers[key] = value
+}
+
+func (r *Request) addQuery(key, value string) {
+	if r.Query == nil {
+		r.Query = url.Values{}
+	}
+	r.Query.Add(key, value)
+}
+
+// effectiveHeaders returns the request headers including the stored content
+// type.
+func (r *Request) effectiveHeaders() map[string]string {
+	headers := maps.Clone(r.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	if r.ContentType != "" && headerValue(headers, "Content-Type") == "" {
+		headers["Content-Type"] = r.ContentType
+	}
+
+	return headers
+}
+
+func detectContentType(header, body string) string {
+	if header != "" {
+		return header
+	}
+
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case trimmed == "":
+		return ""
+	case json.Valid([]byte(trimmed)):
+		return "application/json"
+	case strings.HasPrefix(trimmed, "<"):
+		return "application/xml"
+	case formBodyPattern.MatchString(trimmed):
+		return "application/x-www-form-urlencoded"
+	}
+
+	return "text/plain"
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// migrateLegacyCollection converts the untyped collection map of schema
+// version 1 into a Collection.
+func migrateLegacyCollection(legacy map[string]any) *Collection {
+	c := &Collection{}
+	c.Name, _ = legacy["name"].(string)
+	scheme, _ := legacy["scheme"].(string)
+	host, _ := legacy["host"].(string)
+	headers := stringMap(legacy["headers"])
+
+	for _, method := range slices.Sorted(maps.Keys(legacy)) {
+		endpoints, ok := legacy[method].(map[string]any)
+		if !ok || !isHTTPMethod(method) {
+			continue
+		}
+
+		for _, path := range slices.Sorted(maps.Keys(endpoints)) {
+			requestUrl := url.URL{Scheme: scheme, Host: host, Path: path}
+			r := &Request{
+				Name:   fmt.Sprintf("%s %s", method, path),
+				Method: method,
+				URL:    requestUrl.String(),
+			}
+			if len(headers) > 0 {
+				r.Headers = stringMap(headers)
+			}
+			if endpoint, ok := endpoints[path].(map[string]any); ok {
+				r.Body, _ = endpoint["body"].(string)
+			}
+			c.Requests = append(c.Requests, r)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadLegacyCollection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.json")
+	data := `{
+  "version": 1,
+  "collection": {
+    "name": "Shop",
+    "scheme": "https",
+    "host": "shop.example.com",
+    "headers": {"Accept": "application/json"},
+    "POST": {"/users": {"body": "{\"name\": \"Ada\"}"}},
+    "GET": {"/users": {}, "/users/1": {}},
+    "FETCH": {"/users": {}},
+    "notes": "kept out of the requests"
+  }
+}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := loadCollection(path)
+	if err != nil {
+		t.Fatalf("loadCollection() error = %v", err)
+	}
+	if c.Name != "Shop" || len(c.Folders) != 0 {
+		t.Errorf("collection = %q with %d folders", c.Name, len(c.Folders))
+	}
+
+	want := []Request{
+		{Name: "GET /users", Method: "GET", URL: "https://shop.example.com/users", Headers: map[string]string{"Accept": "application/json"}},
+		{Name: "GET /users/1", Method: "GET", URL: "https://shop.example.com/users/1", Headers: map[string]string{"Accept": "application/json"}},
+		{Name: "POST /users", Method: "POST", URL: "https://shop.example.com/users", Headers: map[string]string{"Accept": "application/json"}, Body: `{"name": "Ada"}`},
+	}
+	if len(c.Requests) != len(want) {
+		t.Fatalf("requests = %d, want %d", len(c.Requests), len(want))
+	}
+	for i, r := range c.Requests {
+		if !reflect.DeepEqual(*r, want[i]) {
+			t.Errorf("requests[%d] = %+v, want %+v", i, *r, want[i])
+		}
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() of the migrated collection error = %v", err)
+	}
+
+	// The migrated collection is saved as the current version
+	if err := saveCollection(path, c); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(saved), `"version": 2`) {
+		t.Errorf("saved collection = %s, want version 2", saved)
+	}
+	if reloaded, err := loadCollection(path); err != nil || !reflect.DeepEqual(reloaded, c) {
+		t.Errorf("loadCollection() after save = %+v, %v, want %+v", reloaded, err, c)
+	}
+}
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request Request
+		wantErr string
+	}{
+		{"valid", Request{Method: "GET", URL: "https://example.com/users"}, ""},
+		{"lower case method", Request{Method: "get", URL: "https://example.com"}, ""},
+		{"templated url", Request{Method: "GET", URL: "{{baseUrl}}/users"}, ""},
+		{"templated host", Request{Method: "GET", URL: "https://{{host}}/users"}, ""},
+		{"empty method", Request{URL: "https://example.com"}, `invalid method ""`},
+		{"method with a space", Request{Method: "GET /users", URL: "https://example.com"}, `invalid method "GET /users"`},
+		{"method with a digit", Request{Method: "P0ST", URL: "https://example.com"}, `invalid method "P0ST"`},
+		{"empty url", Request{Method: "GET", URL: " "}, "url is required"},
+		{"relative url", Request{Method: "GET", URL: "/users"}, `url "/users" must be absolute`},
+		{"url without scheme", Request{Method: "GET", URL: "example.com/users"}, `url "example.com/users" must be absolute`},
+		{"url without host", Request{Method: "GET", URL: "https:///users"}, `url "https:///users" must be absolute`},
+		{"invalid url", Request{Method: "GET", URL: "https://example.com/%zz"}, "invalid URL escape"},
+		{"invalid test", Request{Method: "GET", URL: "https://example.com", Tests: []string{"latency < 5"}}, "tests[0]: "},
+		{"invalid capture", Request{Method: "GET", URL: "https://example.com", Captures: []string{"id $.id"}}, "captures[0]: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		headers         map[string]string
+		body            string
+		wantName        string
+		wantURL         string
+		wantQuery       url.Values
+		wantContentType string
+		wantErr         bool
+	}{
+		{
+			name:   "query split from the url",
+			method: "GET", url: "https://example.com/users?page=2&tag=a&tag=b%20c",
+			wantName: "GET /users", wantURL: "https://example.com/users",
+			wantQuery: url.Values{"page": {"2"}, "tag": {"a", "b c"}},
+		},
+		{
+			name:   "templated url",
+			method: "GET", url: "{{baseUrl}}/users?q={{query}}",
+			wantName: "GET {{baseUrl}}/users", wantURL: "{{baseUrl}}/users",
+			wantQuery: url.Values{"q": {"{{query}}"}},
+		},
+		{
+			name:   "templated host",
+			method: "DELETE", url: "https://{{host}}/users/1#top",
+			wantName: "DELETE https://{{host}}/users/1", wantURL: "https://{{host}}/users/1",
+		},
+		{
+			name:   "content type from the header",
+			method: "POST", url: "https://example.com/users",
+			headers:  map[string]string{"content-type": "application/vnd.api+json"},
+			body:     `{"name": "Ada"}`,
+			wantName: "POST /users", wantURL: "https://example.com/users",
+			wantContentType: "application/vnd.api+json",
+		},
+		{
+			name:   "no content type detected from the body",
+			method: "POST", url: "https://example.com/users",
+			body:     `{"name": "Ada"}`,
+			wantName: "POST /users", wantURL: "https://example.com/users",
+		},
+		{
+			name:   "relative url",
+			method: "GET", url: "/users",
+			wantErr: true,
+		},
+		{
+			name:   "invalid url",
+			method: "GET", url: "https://example.com/%zz",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRequest(tt.method, tt.url, tt.headers, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRequest() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if r.Name != tt.wantName || r.URL != tt.wantURL || r.Body != tt.body || r.ContentType != tt.wantContentType {
+				t.Errorf("newRequest() = %q %q %q %q, want %q %q %q %q", r.Name, r.URL, r.ContentType, r.Body, tt.wantName, tt.wantURL, tt.wantContentType, tt.body)
+			}
+			if !reflect.DeepEqual(r.Query, tt.wantQuery) {
+				t.Errorf("query = %v, want %v", r.Query, tt.wantQuery)
+			}
+			if !maps.Equal(r.Headers, tt.headers) || (r.Headers == nil) != (len(tt.headers) == 0) {
+				t.Errorf("headers = %v, want %v", r.Headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestEffectiveHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		request Request
+		want    map[string]string
+	}{
+		{"no headers", Request{}, map[string]string{}},
+		{"stored content type", Request{Headers: map[string]string{"Accept": "*/*"}, ContentType: "application/json"}, map[string]string{"Accept": "*/*", "Content-Type": "application/json"}},
+		{"header wins", Request{Headers: map[string]string{"content-type": "text/csv"}, ContentType: "application/json"}, map[string]string{"content-type": "text/csv"}},
+		{"no stored content type", Request{Headers: map[string]string{"Accept": "*/*"}, Body: "{}"}, map[string]string{"Accept": "*/*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := maps.Clone(tt.request.Headers)
+			if got := tt.request.effectiveHeaders(); !maps.Equal(got, tt.want) {
+				t.Errorf("effectiveHeaders() = %v, want %v", got, tt.want)
+			}
+			if !maps.Equal(tt.request.Headers, headers) {
+				t.Errorf("effectiveHeaders() changed the request headers to %v", tt.request.Headers)
+			}
+		})
+	}
+}
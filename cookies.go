@@ -0,0 +1,291 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+type jarCookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	// HostOnly cookies are only sent to Domain itself, not its subdomains
+	HostOnly bool      `json:"hostOnly,omitempty"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitzero"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+}
+
+// cookieJar is the session cookie jar shared by all requests. Unlike
+// net/http/cookiejar its cookies can be listed and edited.
+type cookieJar struct {
+	mu      sync.Mutex
+	cookies []jarCookie
+}
+
+func cookiesPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "cookies.json"), nil
+}
+
+func loadCookies(path string) ([]jarCookie, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []jarCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return cookies, nil
+}
+
+func saveCookies(path string, cookies []jarCookie) error {
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data, 0o600)
+}
+
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+	for _, c := range cookies {
+		jc := jarCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   host,
+			HostOnly: true,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+
+		if c.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+			// Servers may only set cookies for their own domain
+			if host != domain && !strings.HasSuffix(host, "."+domain) {
+				continue
+			}
+			// and not for a public suffix like com or co.uk, shared by other
+			// sites, unless it is the host itself
+			if ps, _ := publicsuffix.PublicSuffix(domain); ps == domain {
+				if host != domain {
+					continue
+				}
+			} else {
+				jc.Domain, jc.HostOnly = domain, false
+			}
+		}
+
+		if !strings.HasPrefix(jc.Path, "/") {
+			jc.Path = defaultCookiePath(u.Path)
+		}
+
+		switch {
+		case c.MaxAge < 0:
+			jc.Expires = now.Add(-time.Second)
+		case c.MaxAge > 0:
+			jc.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+
+		j.set(jc, now)
+	}
+}
+
+// set replaces the cookie with the same name, domain and path, or removes it
+// if jc has already expired.
+func (j *cookieJar) set(jc jarCookie, now time.Time) {
+	j.cookies = slices.DeleteFunc(j.cookies, func(c jarCookie) bool {
+		return c.Name == jc.Name && c.Domain == jc.Domain && c.Path == jc.Path
+	})
+
+	if jc.Expires.IsZero() || jc.Expires.After(now) {
+		j.cookies = append(j.cookies, jc)
+	}
+}
+
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	requestPath := cmp.Or(u.Path, "/")
+	now := time.Now()
+
+	var matches []jarCookie
+	for _, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		if c.Secure && u.Scheme != "https" {
+			continue
+		}
+		if host != c.Domain && (c.HostOnly || !strings.HasSuffix(host, "."+c.Domain)) {
+			continue
+		}
+		if !cookiePathMatches(requestPath, c.Path) {
+			continue
+		}
+		matches = append(matches, c)
+	}
+
+	// More specific paths go first
+	slices.SortStableFunc(matches, func(a, b jarCookie) int {
+		return len(b.Path) - len(a.Path)
+	})
+
+	cookies := make([]*http.Cookie, len(matches))
+	for i, c := range matches {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+
+	return cookies
+}
+
+// all returns the unexpired cookies sorted by domain, path and name.
+func (j *cookieJar) all() []jarCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	cookies := slices.DeleteFunc(slices.Clone(j.cookies), func(c jarCookie) bool {
+		return !c.Expires.IsZero() && !c.Expires.After(now)
+	})
+	slices.SortFunc(cookies, func(a, b jarCookie) int {
+		return cmp.Or(cmp.Compare(a.Domain, b.Domain), cmp.Compare(a.Path, b.Path), cmp.Compare(a.Name, b.Name))
+	})
+
+	return cookies
+}
+
+func (j *cookieJar) replace(cookies []jarCookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.cookies = nil
+	now := time.Now()
+	for _, c := range cookies {
+		j.set(c, now)
+	}
+}
+
+func defaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") || strings.Count(requestPath, "/") == 1 {
+		return "/"
+	}
+
+	return path.Dir(requestPath)
+}
+
+func cookiePathMatches(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+
+	return strings.HasPrefix(requestPath, cookiePath) &&
+		(strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/')
+}
+
+// formatCookies renders the cookies for the cookies editor as Set-Cookie
+// lines grouped under a [domain] header. Lines without a Domain attribute are
+// host-only cookies of the group's domain.
+func formatCookies(cookies []jarCookie) string {
+	var b strings.Builder
+	domain := ""
+	for _, c := range cookies {
+		if c.Domain != domain || b.Len() == 0 {
+			if b.Len() > 0 {
+				b.WriteRune('\n')
+			}
+			domain = c.Domain
+			fmt.Fprintf(&b, "[%s]\n", domain)
+		}
+
+		hc := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if !c.HostOnly {
+			hc.Domain = c.Domain
+		}
+		b.WriteString(hc.String() + "\n")
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func parseCookies(text string) ([]jarCookie, error) {
+	var cookies []jarCookie
+	domain := ""
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			domain = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		case domain == "":
+			return nil, fmt.Errorf("line %d: expected a [domain] header", i+1)
+		}
+
+		c, err := http.ParseSetCookie(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		jc := jarCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   domain,
+			HostOnly: c.Domain == "",
+			Path:     cmp.Or(c.Path, "/"),
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.Domain != "" {
+			jc.Domain = strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		}
+		if c.MaxAge > 0 {
+			jc.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		cookies = append(cookies, jc)
+	}
+
+	return cookies, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cookieNames(cookies []*http.Cookie) string {
+	names := make([]string, len(cookies))
+	for i, c := range cookies {
+		names[i] = c.Name + "=" + c.Value
+	}
+
+	return strings.Join(names, "; ")
+}
+
+func TestCookieJarDomains(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   string
+		cookie *http.Cookie
+		to     string
+		want   string
+	}{
+		{"host only", "https://example.com/", &http.Cookie{Name: "a", Value: "1"}, "https://example.com/", "a=1"},
+		{"host only not sent to subdomains", "https://example.com/", &http.Cookie{Name: "a", Value: "1"}, "https://api.example.com/", ""},
+		{"domain sent to subdomains", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: ".example.com"}, "https://api.example.com/", "a=1"},
+		{"subdomain sets parent domain", "https://api.example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "example.com"}, "https://www.example.com/", "a=1"},
+		{"other domain", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "example.org"}, "https://example.org/", ""},
+		{"suffix without a dot", "https://badexample.com/", &http.Cookie{Name: "a", Value: "1", Domain: "example.com"}, "https://example.com/", ""},
+		{"public suffix", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Domain: "com"}, "https://other.com/", ""},
+		{"multi-label public suffix", "https://shop.example.co.uk/", &http.Cookie{Name: "a", Value: "1", Domain: ".co.uk"}, "https://other.co.uk/", ""},
+		{"public suffix of its own host", "https://github.io/", &http.Cookie{Name: "a", Value: "1", Domain: "github.io"}, "https://github.io/", "a=1"},
+		{"public suffix host only", "https://github.io/", &http.Cookie{Name: "a", Value: "1", Domain: "github.io"}, "https://user.github.io/", ""},
+		{"private domain under a public suffix", "https://user.github.io/", &http.Cookie{Name: "a", Value: "1", Domain: "user.github.io"}, "https://www.user.github.io/", "a=1"},
+		{"secure over http", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Secure: true}, "http://example.com/", ""},
+		{"expired", "https://example.com/", &http.Cookie{Name: "a", Value: "1", MaxAge: -1}, "https://example.com/", ""},
+		{"path", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Path: "/api"}, "https://example.com/api/users", "a=1"},
+		{"other path", "https://example.com/", &http.Cookie{Name: "a", Value: "1", Path: "/api"}, "https://example.com/apiv2", ""},
+		{"default path", "https://example.com/api/users", &http.Cookie{Name: "a", Value: "1"}, "https://example.com/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, _ := url.Parse(tt.from)
+			to, _ := url.Parse(tt.to)
+			jar := &cookieJar{}
+			jar.SetCookies(from, []*http.Cookie{tt.cookie})
+			if got := cookieNames(jar.Cookies(to)); got != tt.want {
+				t.Errorf("Cookies() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieJarReplacesAndOrders(t *testing.T) {
+	u, _ := url.Parse("https://example.com/api/users")
+	jar := &cookieJar{}
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "a", Value: "1", Path: "/"},
+		{Name: "b", Value: "2", Path: "/api"},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "3", Path: "/"}})
+
+	if got, want := cookieNames(jar.Cookies(u)), "b=2; a=3"; got != want {
+		t.Errorf("Cookies() = %q, want %q", got, want)
+	}
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "b", Value: "", Path: "/api", MaxAge: -1}})
+	if got, want := cookieNames(jar.Cookies(u)), "a=3"; got != want {
+		t.Errorf("Cookies() after deleting b = %q, want %q", got, want)
+	}
+}
+
+func TestCookiesRoundTrip(t *testing.T) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	cookies := []jarCookie{
+		{Name: "a", Value: "1", Domain: "example.com", HostOnly: true, Path: "/"},
+		{Name: "b", Value: "2", Domain: "example.com", Path: "/api", Expires: expires, Secure: true, HttpOnly: true},
+		{Name: "c", Value: "3", Domain: "example.org", HostOnly: true, Path: "/"},
+	}
+
+	got, err := parseCookies(formatCookies(cookies))
+	if err != nil {
+		t.Fatalf("parseCookies() error = %v", err)
+	}
+	if !slices.EqualFunc(got, cookies, func(a, b jarCookie) bool {
+		return a.Name == b.Name && a.Value == b.Value && a.Domain == b.Domain && a.HostOnly == b.HostOnly &&
+			a.Path == b.Path && a.Expires.Equal(b.Expires) && a.Secure == b.Secure && a.HttpOnly == b.HttpOnly
+	}) {
+		t.Errorf("parseCookies(formatCookies()) = %+v, want %+v", got, cookies)
+	}
+}
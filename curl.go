@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// curlFlagsWithValue are the flags that consume the next argument, used to
+// skip options that postui does not translate.
+var curlFlagsWithValue = map[string]bool{
+	"-X": true, "--request": true, "-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true, "--data-ascii": true, "--data-urlencode": true,
+	"-u": true, "--user": true, "-b": true, "--cookie": true, "-A": true, "--user-agent": true,
+	"-e": true, "--referer": true, "-F": true, "--form": true, "--url": true,
+	"-o": true, "--output": true, "-x": true, "--proxy": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "-w": true, "--write-out": true, "--cacert": true, "-E": true, "--cert": true,
+	"--key": true, "--max-redirs": true, "--retry": true, "-c": true, "--cookie-jar": true, "-r": true, "--range": true,
+	"--retry-delay": true, "--retry-max-time": true, "--resolve": true, "--connect-to": true, "-T": true, "--upload-file": true,
+	"-K": true, "--config": true, "-U": true, "--proxy-user": true, "--noproxy": true, "--interface": true, "--limit-rate": true,
+	"-y": true, "--speed-time": true, "-Y": true, "--speed-limit": true, "-z": true, "--time-cond": true, "-C": true, "--continue-at": true,
+	"--expect100-timeout": true, "--keepalive-time": true, "--max-filesize": true, "--netrc-file": true, "--dns-servers": true,
+	"--oauth2-bearer": true, "--json": true, "--proxy-header": true, "--cert-type": true, "--key-type": true, "--pass": true,
+	"--capath": true, "--ciphers": true, "--tls-max": true, "--proto": true, "--proto-redir": true, "-D": true, "--dump-header": true,
+	"--trace": true, "--trace-ascii": true, "--stderr": true, "--variable": true, "--local-port": true,
+}
+
+// curlIgnoredFlags are boolean flags that are not translated, unlike unknown
+// flags they never take a value.
+var curlIgnoredFlags = map[string]bool{
+	"--http1.0": true, "--http1.1": true, "--http2": true, "--http2-prior-knowledge": true, "--http3": true,
+	"-g": true, "--globoff": true, "-4": true, "--ipv4": true, "-6": true, "--ipv6": true, "-N": true, "--no-buffer": true,
+	"--no-keepalive": true, "--tcp-nodelay": true, "--path-as-is": true, "--fail-with-body": true, "-#": true, "--progress-bar": true,
+	"--no-progress-meter": true, "-O": true, "--remote-name": true, "-J": true, "--remote-header-name": true, "--raw": true,
+	"--tr-encoding": true, "--digest": true, "--basic": true, "--ntlm": true, "--negotiate": true, "--anyauth": true,
+	"--ssl": true, "--ssl-reqd": true, "--tlsv1": true, "--tlsv1.2": true, "--tlsv1.3": true, "-q": true, "--disable": true,
+}
+
+type curlRequest struct {
+	method   string
+	url      string
+	headers  map[string]string
+	body     string
+	settings *Settings
+	warnings []string
+}
+
+func parseCurl(command string) (*curlRequest, error) {
+	args, err := shellSplit(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	// Like curl, redirects are only followed with -L
+	noRedirects := false
+	c := &curlRequest{headers: map[string]string{}, settings: &Settings{FollowRedirects: &noRedirects}}
+	var data []string
+	get := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			// Only the first url is sent, so a stray value can't replace it
+			if c.url != "" {
+				c.warnings = append(c.warnings, fmt.Sprintf("argument %s is ignored", arg))
+				continue
+			}
+			c.url = arg
+			continue
+		}
+
+		flag, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			flag, value, hasValue = strings.Cut(arg, "=")
+		} else if len(arg) > 2 {
+			// Combined short flags like -sSL or -XPOST, the first flag that
+			// takes a value takes the rest of the argument
+			j := 1
+			for ; j < len(arg) && !curlFlagsWithValue["-"+arg[j:j+1]]; j++ {
+				c.applyBooleanFlag("-"+arg[j:j+1], &get)
+			}
+			if j == len(arg) {
+				continue
+			}
+			flag, value, hasValue = "-"+arg[j:j+1], arg[j+1:], j+1 < len(arg)
+		}
+
+		if !curlFlagsWithValue[flag] {
+			// An unknown long flag may take a value, the next argument is
+			// skipped unless it looks like the url
+			known := c.applyBooleanFlag(flag, &get)
+			if !known && !hasValue && strings.HasPrefix(flag, "--") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && !looksLikeURL(args[i+1]) {
+				i++
+			}
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("curl option %s requires a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "-X", "--request":
+			c.method = strings.ToUpper(value)
+		case "-H", "--header":
+			key, headerValue, _ := strings.Cut(value, ":")
+			c.headers[strings.TrimSpace(key)] = strings.TrimSpace(headerValue)
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			if strings.HasPrefix(value, "@") && flag != "--data-raw" {
+				c.warnings = append(c.warnings, fmt.Sprintf("reading data from file %s is not supported", value[1:]))
+				continue
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			name, content, found := strings.Cut(value, "=")
+			if !found {
+				data = append(data, url.QueryEscape(name))
+			} else {
+				data = append(data, name+"="+url.QueryEscape(content))
+			}
+		case "-u", "--user":
+			c.headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(value))
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				c.warnings = append(c.warnings, fmt.Sprintf("reading cookies from file %s is not supported", value))
+				continue
+			}
+			c.headers["Cookie"] = value
+		case "-A", "--user-agent":
+			c.headers["User-Agent"] = value
+		case "-e", "--referer":
+			c.headers["Referer"] = value
+		case "--url":
+			c.url = value
+		case "-m", "--max-time":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value %q", flag, value)
+			}
+			c.settings.Timeout = time.Duration(seconds * float64(time.Second)).String()
+		case "--max-redirs":
+			if err := c.settings.set("maxRedirects", value); err != nil {
+				return nil, err
+			}
+		case "-x", "--proxy":
+			if !strings.Contains(value, "://") {
+				value = "http://" + value
+			}
+			c.settings.Proxy = value
+		case "--cacert":
+			c.settings.CACert = value
+		case "-E", "--cert":
+			c.settings.ClientCert = value
+		case "--key":
+			c.settings.ClientKey = value
+		case "-F", "--form":
+			c.warnings = append(c.warnings, "multipart form data is not supported")
+		default:
+			c.warnings = append(c.warnings, fmt.Sprintf("option %s is ignored", flag))
+		}
+	}
+
+	if c.url == "" {
+		return nil, errors.New("curl command has no url")
+	}
+
+	body := strings.Join(data, "&")
+	if get && body != "" {
+		separator := "?"
+		if strings.Contains(c.url, "?") {
+			separator = "&"
+		}
+		c.url += separator + body
+		body = ""
+	}
+	c.body = body
+
+	if c.method == "" {
+		c.method = http.MethodGet
+		if c.body != "" {
+			c.method = http.MethodPost
+		}
+	}
+	if c.body != "" && headerValue(c.headers, "Content-Type") == "" {
+		c.headers["Content-Type"] = "application/x-www-form-urlencoded"
+	}
+
+	return c, nil
+}
+
+// applyBooleanFlag applies a flag without a value, and reports whether it is
+// a known one.
+func (c *curlRequest) applyBooleanFlag(flag string, get *bool) bool {
+	switch flag {
+	case "-k", "--insecure":
+		insecure := true
+		c.settings.Insecure = &insecure
+	case "-L", "--location":
+		follow := true
+		c.settings.FollowRedirects = &follow
+	case "-G", "--get":
+		*get = true
+	case "-I", "--head":
+		c.method = http.MethodHead
+	case "--compressed":
+		// Go's transport negotiates gzip on its own
+	case "-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include", "-f", "--fail":
+	default:
+		c.warnings = append(c.warnings, fmt.Sprintf("option %s is ignored", flag))
+		return curlIgnoredFlags[flag]
+	}
+
+	return true
+}
+
+// looksLikeURL reports whether the argument after an unknown flag is the url
+// rather than the value of the flag.
+func looksLikeURL(s string) bool {
+	if strings.Contains(s, "://") || strings.HasPrefix(s, "{{") {
+		return true
+	}
+
+	u, err := url.Parse("http://" + s)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+
+	return host == "localhost" || net.ParseIP(host) != nil || strings.Contains(host, ".") && strings.IndexFunc(host, unicode.IsLetter) >= 0
+}
+
+// shellSplit splits a command line the way a POSIX shell would, including
+// $'...' strings as copied from browser devtools.
+func shellSplit(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] != '\n' && runes[i] != '\r' {
+				current.WriteRune(runes[i])
+				inArg = true
+			}
+		case r == '\'':
+			end := slices.Index(runes[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(string(runes[i+1 : i+1+end]))
+			i += end + 1
+			inArg = true
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			i += 2
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					current.WriteString(ansiCEscape(runes[i]))
+					continue
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated $' quote")
+			}
+			inArg = true
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				current.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+func ansiCEscape(r rune) string {
+	switch r {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case '0':
+		return "\x00"
+	}
+
+	return string(r)
+}
+
+// formatCurl renders a request as a copy-pasteable curl command.
+func formatCurl(req *http.Request, body string) string {
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != http.MethodGet || body != "" {
+		fmt.Fprintf(&b, " -X %s", req.Method)
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(req.URL.String()))
+
+	eachHeader(req.Header, func(key, value string) {
+		fmt.Fprintf(&b, " \\\n  -H %s", shellQuote(key+": "+value))
+	})
+
+	if body != "" {
+		fmt.Fprintf(&b, " \\\n  --data-raw %s", shellQuote(body))
+	}
+
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShellSplit(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{"curl  https://example.com", []string{"curl", "https://example.com"}, false},
+		{`-H 'Accept: */*' -d "a \"b\" \$c"`, []string{"-H", "Accept: */*", "-d", `a "b" $c`}, false},
+		{"a\\ b c\\\n  d", []string{"a b", "c", "d"}, false},
+		{`$'line\none\ttab\'s'`, []string{"line\none\ttab's"}, false},
+		{`'it'\''s' ""`, []string{"it's", ""}, false},
+		{`"日本"語`, []string{"日本語"}, false},
+		{`'open`, nil, true},
+		{`"open`, nil, true},
+		{`$'open`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := shellSplit(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("shellSplit(%q) error = %v, want error %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("shellSplit(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		name            string
+		command         string
+		method          string
+		url             string
+		headers         map[string]string
+		body            string
+		followRedirects bool
+		warnings        int
+	}{
+		{
+			name:    "get",
+			command: "curl https://example.com/users",
+			method:  "GET", url: "https://example.com/users", headers: map[string]string{},
+		},
+		{
+			name:    "data",
+			command: `curl -X put 'https://example.com/users/1' -H 'Content-Type: application/json' --data-raw '{"name":"a"}'`,
+			method:  "PUT", url: "https://example.com/users/1",
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    `{"name":"a"}`,
+		},
+		{
+			name:    "form data",
+			command: "curl https://example.com/login -d user=a -d pass=b --data-urlencode 'q=a b'",
+			method:  "POST", url: "https://example.com/login",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:    "user=a&pass=b&q=a+b",
+		},
+		{
+			name:    "get data",
+			command: "curl -G https://example.com/search?lang=en -d q=go",
+			method:  "GET", url: "https://example.com/search?lang=en&q=go", headers: map[string]string{},
+		},
+		{
+			name:    "follow redirects",
+			command: "curl -sSL https://example.com",
+			method:  "GET", url: "https://example.com", headers: map[string]string{},
+			followRedirects: true,
+		},
+		{
+			name:    "location",
+			command: "curl --location https://example.com",
+			method:  "GET", url: "https://example.com", headers: map[string]string{},
+			followRedirects: true,
+		},
+		{
+			name:    "user and agent",
+			command: "curl -u admin:secret -A postui --url=https://example.com -I",
+			method:  "HEAD", url: "https://example.com",
+			headers: map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0", "User-Agent": "postui"},
+		},
+		{
+			name:    "unsupported",
+			command: "curl https://example.com -F file=@a.txt -d @body.json --http2",
+			method:  "GET", url: "https://example.com", headers: map[string]string{},
+			warnings: 3,
+		},
+		{
+			name:    "options with values",
+			command: "curl --retry-delay 3 https://example.com --resolve example.com:443:127.0.0.1 -T file.txt --json '{}'",
+			method:  "GET", url: "https://example.com", headers: map[string]string{},
+			warnings: 4,
+		},
+		{
+			name:    "combined short flags with a value",
+			command: "curl -sSLo out.json https://example.com -XPUT",
+			method:  "PUT", url: "https://example.com", headers: map[string]string{},
+			followRedirects: true,
+			warnings:        1,
+		},
+		{
+			name:    "unknown option with a value",
+			command: "curl --frobnicate 3.5 https://example.com --unheard-of value",
+			method:  "GET", url: "https://example.com", headers: map[string]string{},
+			warnings: 2,
+		},
+		{
+			name:    "unknown option before the url",
+			command: "curl --frobnicate example.com/users",
+			method:  "GET", url: "example.com/users", headers: map[string]string{},
+			warnings: 1,
+		},
+		{
+			name:    "ignored option before the url",
+			command: "curl --http1.1 api/users",
+			method:  "GET", url: "api/users", headers: map[string]string{},
+			warnings: 1,
+		},
+		{
+			name:    "extra argument",
+			command: "curl https://example.com/users https://example.com/other",
+			method:  "GET", url: "https://example.com/users", headers: map[string]string{},
+			warnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCurl(tt.command)
+			if err != nil {
+				t.Fatalf("parseCurl() error = %v", err)
+			}
+			if c.method != tt.method || c.url != tt.url || c.body != tt.body {
+				t.Errorf("parseCurl() = %s %s %q, want %s %s %q", c.method, c.url, c.body, tt.method, tt.url, tt.body)
+			}
+			if !maps.Equal(c.headers, tt.headers) {
+				t.Errorf("headers = %v, want %v", c.headers, tt.headers)
+			}
+			if c.settings.FollowRedirects == nil || *c.settings.FollowRedirects != tt.followRedirects {
+				t.Errorf("followRedirects = %v, want %v", c.settings.FollowRedirects, tt.followRedirects)
+			}
+			if len(c.warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", c.warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestParseCurlSettings(t *testing.T) {
+	c, err := parseCurl("curl -k -m 2.5 --max-redirs 3 -x localhost:8080 --cacert ca.pem -E client.pem --key client.key https://example.com")
+	if err != nil {
+		t.Fatalf("parseCurl() error = %v", err)
+	}
+
+	s := c.settings
+	if s.Insecure == nil || !*s.Insecure || s.Timeout != "2.5s" || s.MaxRedirects == nil || *s.MaxRedirects != 3 {
+		t.Errorf("settings = %+v", s)
+	}
+	if s.Proxy != "http://localhost:8080" || s.CACert != "ca.pem" || s.ClientCert != "client.pem" || s.ClientKey != "client.key" {
+		t.Errorf("settings = %+v", s)
+	}
+
+	for _, command := range []string{"curl -X POST", "curl https://example.com -H", "curl -m soon https://example.com", "curl 'https://example.com"} {
+		if _, err := parseCurl(command); err == nil {
+			t.Errorf("parseCurl(%q) succeeded, want an error", command)
+		}
+	}
+}
+
+func TestFormatCurl(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://example.com/users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	body := `{"name":"it's"}`
+
+	command := formatCurl(req, body)
+	c, err := parseCurl(command)
+	if err != nil {
+		t.Fatalf("parseCurl(%q) error = %v", command, err)
+	}
+	if c.method != "POST" || c.url != "https://example.com/users" || c.body != body || c.headers["Content-Type"] != "application/json" {
+		t.Errorf("parseCurl(formatCurl()) = %s %s %v %q", c.method, c.url, c.headers, c.body)
+	}
+	if !strings.HasPrefix(command, "curl -X POST 'https://example.com/users'") {
+		t.Errorf("formatCurl() = %q", command)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const environmentSchemaVersion = 1
+
+var (
+	unresolvedStyle = errorStyle.Underline(true)
+	escapeSequence  = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]`)
+)
+
+var variableNamePattern = regexp.MustCompile(`^[^{}\s=]+$`)
+
+type Environment struct {
+	Name      string            `json:"name"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// Secrets names the variables whose values are kept in the vault
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+type environmentFile struct {
+	Version     int          `json:"version"`
+	Environment *Environment `json:"environment"`
+}
+
+func environmentsDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "environments"), nil
+}
+
+func environmentPath(name string) (string, error) {
+	dir, err := environmentsDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, slug(name)+".json"), nil
+}
+
+// listEnvironmentNames returns the names of all stored environments, sorted.
+func listEnvironmentNames() ([]string, error) {
+	dir, err := environmentsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		env, err := loadEnvironment(file)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, env.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func loadEnvironment(path string) (*Environment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file environmentFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	if file.Version != environmentSchemaVersion {
+		return nil, fmt.Errorf("%s: unsupported environment schema version %d", filepath.Base(path), file.Version)
+	}
+
+	if file.Environment == nil {
+		return nil, fmt.Errorf("%s: environment is empty", filepath.Base(path))
+	}
+
+	return file.Environment, nil
+}
+
+func saveEnvironment(path string, env *Environment) error {
+	data, err := json.MarshalIndent(environmentFile{
+		Version:     environmentSchemaVersion,
+		Environment: env,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data, 0o600)
+}
+
+func (e *Environment) isSecret(name string) bool {
+	return e != nil && slices.Contains(e.Secrets, name)
+}
+
+// highlightUnresolved marks the {{name}} placeholders in the rendered view of
+// an input or editor that resolved does not know.
+func highlightUnresolved(view string, resolved func(string) bool) string {
+	lines := strings.Split(view, "\n")
+	for i, line := range lines {
+		text := ansi.Strip(line)
+		var b strings.Builder
+		marked := 0
+		for _, loc := range templatePattern.FindAllStringSubmatchIndex(text, -1) {
+			if resolved(text[loc[2]:loc[3]]) {
+				continue
+			}
+			start, end := ansi.StringWidth(text[:loc[0]]), ansi.StringWidth(text[:loc[1]])
+			b.WriteString(ansi.Cut(line, marked, start))
+			b.WriteString(styleText(ansi.Cut(line, start, end), unresolvedStyle))
+			marked = end
+		}
+		if marked > 0 {
+			b.WriteString(ansi.TruncateLeft(line, marked, ""))
+			lines[i] = b.String()
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// styleText applies style to the text of s and keeps its escape sequences, like
+// those drawing the cursor of an editor.
+func styleText(s string, style lipgloss.Style) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range escapeSequence.FindAllStringIndex(s, -1) {
+		if loc[0] > last {
+			b.WriteString(style.Render(s[last:loc[0]]))
+		}
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	if last < len(s) {
+		b.WriteString(style.Render(s[last:]))
+	}
+
+	return b.String()
+}
+
+// parseVariables reads the NAME=value and "secret NAME=value" lines of the
+// environment editor. Blank lines and lines starting with # are skipped.
+func parseVariables(text string) (variables map[string]string, secrets map[string]string, err error) {
+	variables, secrets = map[string]string{}, map[string]string{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target := variables
+		if rest, found := strings.CutPrefix(line, "secret "); found {
+			target, line = secrets, rest
+		}
+
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || !variableNamePattern.MatchString(name) {
+			return nil, nil, fmt.Errorf("line %d: expected NAME=value or secret NAME=value", i+1)
+		}
+		if _, ok := variables[name]; ok {
+			return nil, nil, fmt.Errorf("line %d: %s is defined twice", i+1, name)
+		}
+		if _, ok := secrets[name]; ok {
+			return nil, nil, fmt.Errorf("line %d: %s is defined twice", i+1, name)
+		}
+		target[name] = strings.TrimSpace(value)
+	}
+
+	return variables, secrets, nil
+}
+
+// formatVariables renders env for the environment editor with its secret
+// values masked.
+func formatVariables(env *Environment) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(env.Variables)) {
+		fmt.Fprintf(&b, "%s=%s\n", name, env.Variables[name])
+	}
+	for _, name := range slices.Sorted(slices.Values(env.Secrets)) {
+		fmt.Fprintf(&b, "secret %s=%s\n", name, secretMask)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// substituteVariables replaces every {{name}} placeholder in s that lookup
+// knows about. Unknown placeholders are left in place and returned by name.
+func substituteVariables(s string, lookup func(string) (string, bool)) (string, []string) {
+	var unresolved []string
+	result := templatePattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := templatePattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := lookup(name); ok {
+			return value
+		}
+		unresolved = append(unresolved, name)
+
+		return placeholder
+	})
+
+	return result, unresolved
+}
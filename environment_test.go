@@ -0,0 +1,87 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"HOST": "example.com", "ID": "7", "EMPTY": ""}[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		s              string
+		want           string
+		wantUnresolved []string
+	}{
+		{"https://{{HOST}}/users/{{ ID }}", "https://example.com/users/7", nil},
+		{"{{EMPTY}}x", "x", nil},
+		{"{{TOKEN}} and {{HOST}} and {{TOKEN}}", "{{TOKEN}} and example.com and {{TOKEN}}", []string{"TOKEN", "TOKEN"}},
+		{"{{}} {{a b}} {HOST}", "{{}} {{a b}} {HOST}", nil},
+	}
+	for _, tt := range tests {
+		got, unresolved := substituteVariables(tt.s, lookup)
+		if got != tt.want || !slices.Equal(unresolved, tt.wantUnresolved) {
+			t.Errorf("substituteVariables(%q) = %q, %v, want %q, %v", tt.s, got, unresolved, tt.want, tt.wantUnresolved)
+		}
+	}
+}
+
+func TestParseVariables(t *testing.T) {
+	variables, secrets, err := parseVariables("# comment\nHOST=example.com\n\n  secret TOKEN = abc=def \nEMPTY=")
+	if err != nil {
+		t.Fatalf("parseVariables() error = %v", err)
+	}
+	if want := map[string]string{"HOST": "example.com", "EMPTY": ""}; !maps.Equal(variables, want) {
+		t.Errorf("variables = %v, want %v", variables, want)
+	}
+	if want := map[string]string{"TOKEN": "abc=def"}; !maps.Equal(secrets, want) {
+		t.Errorf("secrets = %v, want %v", secrets, want)
+	}
+
+	for _, text := range []string{"HOST", "=value", "a b=c"} {
+		if _, _, err := parseVariables(text); err == nil {
+			t.Errorf("parseVariables(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestHighlightUnresolved(t *testing.T) {
+	lipgloss.SetColorProfile(0) // termenv.TrueColor
+	defer lipgloss.SetColorProfile(3)
+
+	resolved := func(name string) bool { return name == "HOST" }
+	mark := func(s string) string { return styleText(s, unresolvedStyle) }
+
+	tests := []struct {
+		name string
+		view string
+		want string
+	}{
+		{"resolved", "│ https://{{HOST}}/", "│ https://{{HOST}}/"},
+		{"unresolved", "│ https://{{HOST}}/{{ ID }}?t={{TOKEN}}", "│ https://{{HOST}}/" + mark("{{ ID }}") + "?t=" + mark("{{TOKEN}}")},
+		{"lines", "Authorization: {{TOKEN}}\nX-Host: {{HOST}}", "Authorization: " + mark("{{TOKEN}}") + "\nX-Host: {{HOST}}"},
+		{"wide runes", "ünï {{ID}} 日本 {{TOKEN}}", "ünï " + mark("{{ID}}") + " 日本 " + mark("{{TOKEN}}")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightUnresolved(tt.view, resolved); got != tt.want {
+				t.Errorf("highlightUnresolved() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The cursor of an editor is kept inside a placeholder
+	cursor := "\x1b[7mD\x1b[0m"
+	got := highlightUnresolved("id={{I"+cursor+"}}", resolved)
+	if ansi.Strip(got) != "id={{ID}}" || !strings.Contains(got, "\x1b[7m"+mark("D")) || !strings.Contains(got, mark("}}")) {
+		t.Errorf("highlightUnresolved() = %q, want the cursor kept", got)
+	}
+}
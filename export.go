@@ -0,0 +1,381 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+const postmanSchemaV21 = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type ExportFormat string
+
+const (
+	ExportPostman  ExportFormat = "postman"
+	ExportInsomnia ExportFormat = "insomnia"
+)
+
+type insomniaExport struct {
+	Type         string             `json:"_type"`
+	ExportFormat int                `json:"__export_format"`
+	ExportDate   string             `json:"__export_date"`
+	ExportSource string             `json:"__export_source"`
+	Resources    []insomniaResource `json:"resources"`
+}
+
+type insomniaResource struct {
+	ID          string             `json:"_id"`
+	Type        string             `json:"_type"`
+	ParentID    *string            `json:"parentId"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Scope       string             `json:"scope,omitempty"`
+	Data        map[string]string  `json:"data,omitempty"`
+	Method      string             `json:"method,omitempty"`
+	URL         string             `json:"url,omitempty"`
+	Body        *insomniaBody      `json:"body,omitempty"`
+	Headers     []insomniaKeyValue `json:"headers,omitempty"`
+	Parameters  []insomniaKeyValue `json:"parameters,omitempty"`
+	// Authentication is the auth of a request, with the auth it inherits
+	// from its folders and the collection filled in
+	Authentication map[string]any `json:"authentication,omitempty"`
+}
+
+type insomniaBody struct {
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+type insomniaKeyValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// parseExportTarget splits the export prompt value "[postman|insomnia] <path>"
+// into its format and path, defaulting to Postman.
+func parseExportTarget(value string) (ExportFormat, string) {
+	first, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	switch ExportFormat(strings.ToLower(first)) {
+	case ExportPostman:
+		return ExportPostman, strings.TrimSpace(rest)
+	case ExportInsomnia:
+		return ExportInsomnia, strings.TrimSpace(rest)
+	}
+
+	return ExportPostman, strings.TrimSpace(value)
+}
+
+func exportCollectionFile(c *Collection, format ExportFormat, path string) error {
+	if path == "" {
+		return fmt.Errorf("missing path to export the %s collection to", format)
+	}
+
+	var export any
+	switch format {
+	case ExportPostman:
+		export = exportPostman(c)
+	case ExportInsomnia:
+		export = exportInsomnia(c, time.Now())
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(expandPath(path), data, 0o644)
+}
+
+func exportPostman(c *Collection) postmanCollection {
+	pc := postmanCollection{
+		Info: postmanInfo{
+			Name:        c.Name,
+			Description: postmanDescription(c.Description),
+			Schema:      postmanSchemaV21,
+		},
+		Item: exportPostmanItems(c.Folders, c.Requests),
+		Auth: exportPostmanAuth(c.Auth),
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(c.Variables)) {
+		pc.Variable = append(pc.Variable, postmanKeyValue{Key: key, Value: c.Variables[key]})
+	}
+
+	return pc
+}
+
+func exportPostmanItems(folders []*Folder, requests []*Request) []postmanItem {
+	items := []postmanItem{}
+	for _, f := range folders {
+		items = append(items, postmanItem{
+			Name:        f.Name,
+			Description: postmanDescription(f.Description),
+			Item:        exportPostmanItems(f.Folders, f.Requests),
+			Auth:        exportPostmanAuth(f.Auth),
+		})
+	}
+
+	for _, r := range requests {
+		pr := &postmanRequest{
+			Method:      r.Method,
+			Description: postmanDescription(r.Description),
+			URL:         postmanURL{Raw: r.FullURL()},
+			Auth:        exportPostmanAuth(r.Auth),
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(r.Query)) {
+			for _, value := range r.Query[key] {
+				pr.URL.Query = append(pr.URL.Query, postmanKeyValue{Key: key, Value: value})
+			}
+		}
+
+		headers := r.effectiveHeaders()
+		for _, key := range slices.Sorted(maps.Keys(headers)) {
+			pr.Header = append(pr.Header, postmanKeyValue{Key: key, Value: headers[key]})
+		}
+
+		if r.Body != "" {
+			pr.Body = &postmanBody{Mode: "raw", Raw: r.Body}
+			if language := postmanLanguage(r.ContentType); language != "" {
+				pr.Body.Options = &postmanBodyOptions{}
+				pr.Body.Options.Raw.Language = language
+			}
+		}
+
+		items = append(items, postmanItem{
+			Name:    r.displayName(),
+			Request: pr,
+		})
+	}
+
+	return items
+}
+
+func exportPostmanAuth(a *Auth) *postmanAuth {
+	if a == nil {
+		return nil
+	}
+
+	params := func(keyValues ...string) []postmanKeyValue {
+		var params []postmanKeyValue
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			params = append(params, postmanKeyValue{Key: keyValues[i], Value: keyValues[i+1], Type: "string"})
+		}
+		return params
+	}
+
+	switch a.Type {
+	case AuthBasic:
+		return &postmanAuth{Type: "basic", Basic: params("username", a.Username, "password", a.Password)}
+	case AuthBearer:
+		return &postmanAuth{Type: "bearer", Bearer: params("token", a.Token)}
+	case AuthAPIKey:
+		return &postmanAuth{Type: "apikey", APIKey: params("key", a.Key, "value", a.Value, "in", cmp.Or(a.In, "header"))}
+	case AuthDigest:
+		return &postmanAuth{Type: "digest", Digest: params("username", a.Username, "password", a.Password)}
+	case AuthOAuth2:
+		grant := "client_credentials"
+		if a.Grant == grantPassword {
+			grant = "password_credentials"
+		}
+		return &postmanAuth{Type: "oauth2", OAuth2: params(
+			"grant_type", grant,
+			"accessTokenUrl", a.TokenURL,
+			"clientId", a.ClientID,
+			"clientSecret", a.ClientSecret,
+			"scope", a.Scope,
+			"username", a.Username,
+			"password", a.Password,
+		)}
+	case AuthAWSv4:
+		return &postmanAuth{Type: "awsv4", AWSv4: params(
+			"accessKey", a.AccessKey,
+			"secretKey", a.SecretKey,
+			"sessionToken", a.SessionToken,
+			"region", a.Region,
+			"service", a.Service,
+		)}
+	}
+
+	return &postmanAuth{Type: "noauth"}
+}
+
+func postmanLanguage(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return "json"
+	case strings.Contains(contentType, "xml"):
+		return "xml"
+	case strings.Contains(contentType, "html"):
+		return "html"
+	case strings.Contains(contentType, "javascript"):
+		return "javascript"
+	case strings.HasPrefix(contentType, "text/"):
+		return "text"
+	}
+
+	return ""
+}
+
+func exportInsomnia(c *Collection, now time.Time) insomniaExport {
+	ids := map[string]int{}
+	newID := func(prefix string) string {
+		ids[prefix]++
+		return fmt.Sprintf("%s_%032x", prefix, ids[prefix])
+	}
+
+	workspaceID := newID("wrk")
+	resources := []insomniaResource{
+		{
+			ID:          workspaceID,
+			Type:        "workspace",
+			Name:        c.Name,
+			Description: c.Description,
+			Scope:       "collection",
+		},
+		{
+			ID:       newID("env"),
+			Type:     "environment",
+			ParentID: &workspaceID,
+			Name:     "Base Environment",
+			Data:     c.Variables,
+		},
+	}
+
+	var addItems func(parentID string, inherited *Auth, folders []*Folder, requests []*Request)
+	addItems = func(parentID string, inherited *Auth, folders []*Folder, requests []*Request) {
+		for _, f := range folders {
+			folderID := newID("fld")
+			resources = append(resources, insomniaResource{
+				ID:          folderID,
+				Type:        "request_group",
+				ParentID:    &parentID,
+				Name:        f.Name,
+				Description: f.Description,
+			})
+			auth := inherited
+			if f.Auth != nil {
+				auth = f.Auth
+			}
+			addItems(folderID, auth, f.Folders, f.Requests)
+		}
+
+		for _, r := range requests {
+			resource := insomniaResource{
+				ID:          newID("req"),
+				Type:        "request",
+				ParentID:    &parentID,
+				Name:        r.displayName(),
+				Description: r.Description,
+				Method:      r.Method,
+				URL:         insomniaTemplate(r.URL),
+				Headers:     []insomniaKeyValue{},
+				Parameters:  []insomniaKeyValue{},
+			}
+
+			auth := inherited
+			if r.Auth != nil {
+				auth = r.Auth
+			}
+			resource.Authentication = exportInsomniaAuth(auth)
+
+			headers := r.effectiveHeaders()
+			for _, key := range slices.Sorted(maps.Keys(headers)) {
+				resource.Headers = append(resource.Headers, insomniaKeyValue{Name: key, Value: insomniaTemplate(headers[key])})
+			}
+
+			for _, key := range slices.Sorted(maps.Keys(r.Query)) {
+				for _, value := range r.Query[key] {
+					resource.Parameters = append(resource.Parameters, insomniaKeyValue{Name: key, Value: insomniaTemplate(value)})
+				}
+			}
+
+			resource.Body = &insomniaBody{}
+			if r.Body != "" {
+				resource.Body = &insomniaBody{MimeType: r.ContentType, Text: insomniaTemplate(r.Body)}
+			}
+
+			resources = append(resources, resource)
+		}
+	}
+	addItems(workspaceID, c.Auth, c.Folders, c.Requests)
+
+	return insomniaExport{
+		Type:         "export",
+		ExportFormat: 4,
+		ExportDate:   now.UTC().Format(time.RFC3339),
+		ExportSource: "postui",
+		Resources:    resources,
+	}
+}
+
+// exportInsomniaAuth converts a to the authentication of an Insomnia request,
+// which has no auth without it.
+func exportInsomniaAuth(a *Auth) map[string]any {
+	if a == nil {
+		return nil
+	}
+
+	auth := func(authType string, keyValues ...string) map[string]any {
+		auth := map[string]any{"type": authType}
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			auth[keyValues[i]] = insomniaTemplate(keyValues[i+1])
+		}
+		return auth
+	}
+
+	switch a.Type {
+	case AuthBasic:
+		return auth("basic", "username", a.Username, "password", a.Password)
+	case AuthBearer:
+		return auth("bearer", "token", a.Token)
+	case AuthAPIKey:
+		addTo := "header"
+		if a.In == "query" {
+			addTo = "queryParams"
+		}
+		return auth("apikey", "key", a.Key, "value", a.Value, "addTo", addTo)
+	case AuthDigest:
+		return auth("digest", "username", a.Username, "password", a.Password)
+	case AuthOAuth2:
+		return auth("oauth2",
+			"grantType", cmp.Or(a.Grant, grantClientCredentials),
+			"accessTokenUrl", a.TokenURL,
+			"clientId", a.ClientID,
+			"clientSecret", a.ClientSecret,
+			"scope", a.Scope,
+			"username", a.Username,
+			"password", a.Password,
+		)
+	case AuthAWSv4:
+		return auth("iam",
+			"accessKeyId", a.AccessKey,
+			"secretAccessKey", a.SecretKey,
+			"sessionToken", a.SessionToken,
+			"region", a.Region,
+			"service", a.Service,
+		)
+	}
+
+	return nil
+}
+
+// insomniaTemplate rewrites {{VAR}} placeholders into Insomnia's {{ _.VAR }}
+// environment syntax.
+func insomniaTemplate(s string) string {
+	return templatePattern.ReplaceAllString(s, "{{ _.$1 }}")
+}
+
+func (r *Request) displayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return r.Method + " " + r.URL
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseExportTarget(t *testing.T) {
+	tests := []struct {
+		value      string
+		wantFormat ExportFormat
+		wantPath   string
+	}{
+		{"out.json", ExportPostman, "out.json"},
+		{"postman ~/out.json", ExportPostman, "~/out.json"},
+		{"  Insomnia  out.json ", ExportInsomnia, "out.json"},
+		{"insomnia", ExportInsomnia, ""},
+		{"my collection.json", ExportPostman, "my collection.json"},
+	}
+	for _, tt := range tests {
+		format, path := parseExportTarget(tt.value)
+		if format != tt.wantFormat || path != tt.wantPath {
+			t.Errorf("parseExportTarget(%q) = %s, %q, want %s, %q", tt.value, format, path, tt.wantFormat, tt.wantPath)
+		}
+	}
+}
+
+var exportTestCollection = &Collection{
+	Name:        "shop",
+	Description: "The shop API",
+	Variables:   map[string]string{"baseUrl": "https://shop.example.com"},
+	Folders: []*Folder{
+		{Name: "users", Requests: []*Request{
+			{
+				Name:        "create user",
+				Method:      "POST",
+				URL:         "{{baseUrl}}/users",
+				Query:       url.Values{"notify": {"true"}},
+				Headers:     map[string]string{"X-Trace": "{{trace}}"},
+				ContentType: "application/json",
+				Body:        `{"name": "{{name}}"}`,
+			},
+		}},
+	},
+	Requests: []*Request{{Method: "GET", URL: "{{baseUrl}}/health"}},
+}
+
+func TestExportPostman(t *testing.T) {
+	data, err := json.Marshal(exportPostman(exportTestCollection))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, warnings, err := importPostman(data)
+	if err != nil || len(warnings) > 0 {
+		t.Fatalf("importPostman() of the export = %v, %v", warnings, err)
+	}
+	if c.Name != "shop" || c.Description != "The shop API" || c.Variables["baseUrl"] != "https://shop.example.com" {
+		t.Errorf("collection = %q %q %v", c.Name, c.Description, c.Variables)
+	}
+	if len(c.Folders) != 1 || len(c.Folders[0].Requests) != 1 || len(c.Requests) != 1 {
+		t.Fatalf("exported items = %+v %+v", c.Folders, c.Requests)
+	}
+
+	r := c.Folders[0].Requests[0]
+	if r.Name != "create user" || r.Method != "POST" || r.URL != "{{baseUrl}}/users" || r.Query.Encode() != "notify=true" {
+		t.Errorf("request = %q %s %s ? %s", r.Name, r.Method, r.URL, r.Query.Encode())
+	}
+	if r.Headers["X-Trace"] != "{{trace}}" || r.Headers["Content-Type"] != "application/json" {
+		t.Errorf("headers = %v", r.Headers)
+	}
+	if r.Body != `{"name": "{{name}}"}` || r.ContentType != "application/json" {
+		t.Errorf("body = %q %q", r.ContentType, r.Body)
+	}
+	if got := c.Requests[0].Name; got != "GET {{baseUrl}}/health" {
+		t.Errorf("unnamed request exported as %q", got)
+	}
+}
+
+func TestExportInsomnia(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	export := exportInsomnia(exportTestCollection, now)
+	if export.Type != "export" || export.ExportFormat != 4 || export.ExportDate != "2026-01-02T03:04:05Z" {
+		t.Errorf("export = %s %d %s", export.Type, export.ExportFormat, export.ExportDate)
+	}
+
+	byName := map[string]insomniaResource{}
+	for _, resource := range export.Resources {
+		byName[resource.Name] = resource
+	}
+	workspace, folder, create := byName["shop"], byName["users"], byName["create user"]
+
+	if workspace.Type != "workspace" || workspace.ParentID != nil {
+		t.Errorf("workspace = %+v", workspace)
+	}
+	if env := byName["Base Environment"]; env.Type != "environment" || *env.ParentID != workspace.ID || env.Data["baseUrl"] != "https://shop.example.com" {
+		t.Errorf("environment = %+v", env)
+	}
+	if folder.Type != "request_group" || *folder.ParentID != workspace.ID {
+		t.Errorf("folder = %+v", folder)
+	}
+	if create.Type != "request" || *create.ParentID != folder.ID || create.URL != "{{ _.baseUrl }}/users" {
+		t.Errorf("request = %+v", create)
+	}
+	if got := create.Parameters; len(got) != 1 || got[0] != (insomniaKeyValue{Name: "notify", Value: "true"}) {
+		t.Errorf("parameters = %v", got)
+	}
+	wantHeaders := []insomniaKeyValue{{Name: "Content-Type", Value: "application/json"}, {Name: "X-Trace", Value: "{{ _.trace }}"}}
+	if got := create.Headers; len(got) != 2 || got[0] != wantHeaders[0] || got[1] != wantHeaders[1] {
+		t.Errorf("headers = %v, want %v", got, wantHeaders)
+	}
+	if create.Body == nil || create.Body.MimeType != "application/json" || create.Body.Text != `{"name": "{{ _.name }}"}` {
+		t.Errorf("body = %+v", create.Body)
+	}
+	if health := byName["GET {{baseUrl}}/health"]; *health.ParentID != workspace.ID {
+		t.Errorf("request without folder = %+v", health)
+	}
+}
+
+func TestExportInsomniaAuth(t *testing.T) {
+	c := &Collection{
+		Name: "api",
+		Auth: &Auth{Type: AuthBearer, Token: "{{TOKEN}}"},
+		Folders: []*Folder{
+			{Name: "keys", Auth: &Auth{Type: AuthAPIKey, Key: "X-Key", Value: "k", In: "query"}, Requests: []*Request{
+				{Name: "inherits folder", Method: "GET", URL: "https://example.com/a"},
+				{Name: "none", Method: "GET", URL: "https://example.com/b", Auth: &Auth{Type: AuthNone}},
+			}},
+		},
+		Requests: []*Request{
+			{Name: "inherits collection", Method: "GET", URL: "https://example.com/c"},
+			{Name: "aws", Method: "GET", URL: "https://example.com/d", Auth: &Auth{Type: AuthAWSv4, AccessKey: "AK", SecretKey: "SK", Region: "eu-west-1", Service: "s3"}},
+		},
+	}
+
+	want := map[string]string{
+		"inherits folder":     `{"addTo":"queryParams","key":"X-Key","type":"apikey","value":"k"}`,
+		"none":                `null`,
+		"inherits collection": `{"token":"{{ _.TOKEN }}","type":"bearer"}`,
+		"aws":                 `{"accessKeyId":"AK","region":"eu-west-1","secretAccessKey":"SK","service":"s3","sessionToken":"","type":"iam"}`,
+	}
+
+	export := exportInsomnia(c, time.Now())
+	for _, resource := range export.Resources {
+		if resource.Type != "request" {
+			continue
+		}
+		got, err := json.Marshal(resource.Authentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[resource.Name] {
+			t.Errorf("authentication of %q = %s, want %s", resource.Name, got, want[resource.Name])
+		}
+		delete(want, resource.Name)
+	}
+	if len(want) > 0 {
+		t.Errorf("requests not exported: %v", want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+const (
+	// filterTimeout stops jq programs that take too long, like last(range(1e9))
+	filterTimeout = 2 * time.Second
+	// maxFilterOutputs stops jq programs with endless results, like repeat(.)
+	maxFilterOutputs = 10000
+)
+
+// filterBody applies a JSONPath expression, when expr starts with $, or else
+// a jq program to a json body and returns the results as indented json.
+func filterBody(ctx context.Context, expr, body string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(expr), "$") {
+		results, err := queryJSONPath(expr, body)
+		if err != nil {
+			return "", err
+		}
+		if len(results) == 1 {
+			return formatJSON(results[0])
+		}
+		return formatJSON(append([]any{}, results...))
+	}
+
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return "", fmt.Errorf("jq: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return "", fmt.Errorf("jq: %w", err)
+	}
+
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return "", err
+	}
+
+	// Like jq, every result is printed on its own
+	var outputs []string
+	iter := code.RunWithContext(ctx, doc)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			var haltErr *gojq.HaltError
+			switch {
+			case errors.As(err, &haltErr) && haltErr.Value() == nil:
+				return strings.Join(outputs, "\n"), nil
+			case errors.Is(err, context.DeadlineExceeded):
+				return "", errors.New("jq: timed out")
+			}
+			return "", fmt.Errorf("jq: %w", err)
+		}
+		if len(outputs) == maxFilterOutputs {
+			return "", fmt.Errorf("jq: stopped after %d results", maxFilterOutputs)
+		}
+
+		data, err := gojq.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			return "", err
+		}
+		outputs = append(outputs, indented.String())
+	}
+
+	return strings.Join(outputs, "\n"), nil
+}
+
+func formatJSON(v any) (string, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
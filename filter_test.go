@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterBody(t *testing.T) {
+	body := `{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}`
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    string
+		wantErr string
+		// timeout is only set for the cases that need a deadline to stop
+		timeout time.Duration
+	}{
+		{name: "jq field", expr: ".items[0].name", want: `"a"`},
+		{name: "jq outputs on their own", expr: ".items[].id", want: "1\n2"},
+		{name: "jq object", expr: ".items[1]", want: "{\n  \"id\": 2,\n  \"name\": \"b\"\n}"},
+		{name: "jq halt", expr: ".items[].id, halt", want: "1\n2"},
+		{name: "jsonpath single", expr: "$.items[0].id", want: "1"},
+		{name: "jsonpath several", expr: "$.items[*].name", want: "[\n  \"a\",\n  \"b\"\n]"},
+		{name: "jq syntax error", expr: ".items[", wantErr: "jq:"},
+		{name: "jq error", expr: ".items | keys | .[0] | error", wantErr: "jq:"},
+		{name: "endless results", expr: "repeat(.)", wantErr: "jq: stopped after 10000 results"},
+		{name: "endless program", expr: "last(range(1e10))", wantErr: "jq: timed out", timeout: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			got, err := filterBody(ctx, tt.expr, body)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("filterBody() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filterBody() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("filterBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxHistoryEntries      = 500
+	maxHistoryResponseBody = 64 * 1024
+)
+
+type HistoryEntry struct {
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	StatusCode      int               `json:"statusCode,omitempty"`
+	ResponseTime    int64             `json:"responseTime,omitempty"`
+	ResponseHeaders string            `json:"responseHeaders,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Timeline        string            `json:"timeline,omitempty"`
+	// Truncated is set when the response body exceeded maxHistoryResponseBody
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func historyPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// loadHistory reads the history file, oldest entry first. Lines that cannot be
+// parsed are skipped so a single bad write does not lose the whole history.
+func loadHistory(path string) ([]HistoryEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	for line := range bytes.Lines(data) {
+		var entry HistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// appendHistory adds entry to the history file, rewriting it with only the
+// newest maxHistoryEntries once it grows too long.
+func appendHistory(path string, entries []HistoryEntry, entry HistoryEntry) ([]HistoryEntry, error) {
+	entries = append(entries, entry)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+
+		var b bytes.Buffer
+		for _, e := range entries {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return entries, err
+			}
+			b.Write(line)
+			b.WriteByte('\n')
+		}
+
+		return entries, writeFileAtomic(path, b.Bytes(), 0o600)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entries, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return entries, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return entries, err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return entries, err
+	}
+
+	return entries, f.Close()
+}
+
+func newHistoryEntry(spec requestSpec, now time.Time) HistoryEntry {
+	return HistoryEntry{
+		Time:    now,
+		Method:  spec.method,
+		URL:     spec.url,
+		Headers: spec.headers,
+		Body:    spec.body,
+	}
+}
+
+func (e *HistoryEntry) setResponse(msg responseMsg) {
+	e.StatusCode = msg.statusCode
+	e.ResponseTime = msg.responseTime
+	e.ResponseHeaders = msg.responseHeaders
+	e.ResponseBody = msg.responseBody
+	e.Timeline = msg.timeline
+	if len(e.ResponseBody) > maxHistoryResponseBody {
+		// Cut at the start of a rune so the body stays valid UTF-8
+		end := maxHistoryResponseBody
+		for end > 0 && !utf8.RuneStart(e.ResponseBody[end]) {
+			end--
+		}
+		e.ResponseBody = e.ResponseBody[:end]
+		e.Truncated = true
+	}
+}
+
+func (e *HistoryEntry) matches(filter string) bool {
+	filter = strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(e.summary()), filter)
+}
+
+func (e *HistoryEntry) summary() string {
+	status := "error"
+	if e.StatusCode > 0 {
+		status = fmt.Sprintf("%d %5d ms", e.StatusCode, e.ResponseTime)
+	}
+
+	summary := fmt.Sprintf("%s  %-12s  %-7s %s", e.Time.Local().Format(time.DateTime), status, e.Method, e.URL)
+	if e.Error != "" {
+		summary += "  " + e.Error
+	}
+
+	return summary
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSetResponseTruncates(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantLen   int
+		truncated bool
+	}{
+		{"short", "ok", 2, false},
+		{"at the limit", strings.Repeat("a", maxHistoryResponseBody), maxHistoryResponseBody, false},
+		{"ascii", strings.Repeat("a", maxHistoryResponseBody+1), maxHistoryResponseBody, true},
+		// "€" takes 3 bytes, the limit falls inside one
+		{"multibyte", "ab" + strings.Repeat("€", maxHistoryResponseBody/3+1), 2 + 3*((maxHistoryResponseBody-2)/3), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &HistoryEntry{}
+			e.setResponse(responseMsg{responseBody: tt.body})
+			if len(e.ResponseBody) != tt.wantLen || e.Truncated != tt.truncated {
+				t.Errorf("body of %d bytes, truncated %v, want %d bytes, truncated %v", len(e.ResponseBody), e.Truncated, tt.wantLen, tt.truncated)
+			}
+			if !utf8.ValidString(e.ResponseBody) {
+				t.Error("truncated body is not valid UTF-8")
+			}
+		})
+	}
+}
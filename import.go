@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importCollectionFile reads a collection exported by another tool and
+// converts it into a Collection, returning the items that were skipped.
+func importCollectionFile(path string) (*Collection, []string, error) {
+	data, err := os.ReadFile(expandPath(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isPostmanCollection(data) {
+		return importPostman(data)
+	}
+
+	if doc, err := parseSpecDocument(data); err == nil && isOpenAPISpec(doc) {
+		return importOpenAPI(doc)
+	}
+
+	return nil, nil, errors.New("unrecognized format, expected a Postman v2.1 collection or an OpenAPI 3 / Swagger 2 document")
+}
+
+func expandPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+
+	return path
+}
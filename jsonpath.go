@@ -0,0 +1,631 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed JSONPath expression. It supports names, wildcards,
+// indexes, slices, unions, recursive descent and filters like
+// $..books[?(@.price < 10 && @.author)].title
+type jsonPath []jsonPathSegment
+
+type jsonPathSegment struct {
+	// recursive segments (..) apply to all descendants
+	recursive bool
+	selectors []jsonPathSelector
+}
+
+type jsonPathSelector struct {
+	name     *string
+	index    *int
+	wildcard bool
+	slice    *[3]*int
+	filter   *jsonPathFilter
+}
+
+// jsonPathFilter is a node of a filter expression, either a logical or
+// comparison operator or an operand.
+type jsonPathFilter struct {
+	op          string
+	left, right *jsonPathFilter
+
+	// operands are a path relative to @ or $, or a literal
+	path     jsonPath
+	relative bool
+	absolute bool
+	literal  any
+}
+
+type jsonPathParser struct {
+	expr string
+	pos  int
+}
+
+// queryJSONPath evaluates expr on the json text body.
+func queryJSONPath(expr, body string) ([]any, error) {
+	path, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return path.eval(doc), nil
+}
+
+func decodeJSON(body string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("invalid json: unexpected data after the json value")
+	}
+
+	return doc, nil
+}
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	path, rest, err := parseJSONPathPrefix(expr)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		column := len(strings.TrimSpace(expr)) - len(rest) + 1
+		return nil, fmt.Errorf("jsonpath column %d: unexpected %q", column, rest)
+	}
+
+	return path, nil
+}
+
+// parseJSONPathPrefix parses the JSONPath at the start of s and returns the
+// text after it.
+func parseJSONPathPrefix(s string) (jsonPath, string, error) {
+	p := &jsonPathParser{expr: strings.TrimSpace(s)}
+	if !p.consume("$") {
+		return nil, "", errors.New("jsonpath must start with $")
+	}
+
+	path, err := p.segments()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return path, p.expr[p.pos:], nil
+}
+
+func (p *jsonPathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("jsonpath column %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.pos < len(p.expr) && p.expr[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *jsonPathParser) consume(s string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+
+	return false
+}
+
+func (p *jsonPathParser) segments() (jsonPath, error) {
+	var path jsonPath
+	for p.pos < len(p.expr) {
+		var segment jsonPathSegment
+		switch {
+		case p.consume(".."):
+			segment.recursive = true
+			if strings.HasPrefix(p.expr[p.pos:], "[") {
+				break
+			}
+			fallthrough
+		case p.consume("."):
+			if p.consume("*") {
+				segment.selectors = []jsonPathSelector{{wildcard: true}}
+			} else {
+				name := p.name()
+				if name == "" {
+					return nil, p.errorf("expected a name")
+				}
+				segment.selectors = []jsonPathSelector{{name: &name}}
+			}
+			path = append(path, segment)
+			continue
+		case !strings.HasPrefix(p.expr[p.pos:], "["):
+			return path, nil
+		}
+
+		p.pos++
+		selectors, err := p.selectors()
+		if err != nil {
+			return nil, err
+		}
+		segment.selectors = selectors
+		path = append(path, segment)
+	}
+
+	return path, nil
+}
+
+func (p *jsonPathParser) name() string {
+	start := p.pos
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		if c != '_' && c != '-' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') && c < 0x80 {
+			break
+		}
+		p.pos++
+	}
+
+	return p.expr[start:p.pos]
+}
+
+// selectors parses the comma separated selectors of a bracket, after the [.
+func (p *jsonPathParser) selectors() ([]jsonPathSelector, error) {
+	var selectors []jsonPathSelector
+	for {
+		p.skipSpace()
+		var selector jsonPathSelector
+		switch {
+		case p.consume("*"):
+			selector.wildcard = true
+		case p.consume("?"):
+			p.skipSpace()
+			filter, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			selector.filter = filter
+		case strings.HasPrefix(p.expr[p.pos:], "'"), strings.HasPrefix(p.expr[p.pos:], `"`):
+			name, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			selector.name = &name
+		default:
+			var bounds [3]*int
+			for i := range bounds {
+				p.skipSpace()
+				if n, ok := p.integer(); ok {
+					bounds[i] = &n
+				}
+				p.skipSpace()
+				if i == 2 || !p.consume(":") {
+					if i == 0 && bounds[0] != nil {
+						selector.index = bounds[0]
+					} else if i == 0 {
+						return nil, p.errorf("expected a selector")
+					} else {
+						selector.slice = &bounds
+					}
+					break
+				}
+			}
+		}
+		selectors = append(selectors, selector)
+
+		p.skipSpace()
+		switch {
+		case p.consume(","):
+		case p.consume("]"):
+			return selectors, nil
+		default:
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+func (p *jsonPathParser) integer() (int, bool) {
+	start := p.pos
+	if p.pos < len(p.expr) && p.expr[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.expr) && '0' <= p.expr[p.pos] && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+
+	n, err := strconv.Atoi(p.expr[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+
+	return n, true
+}
+
+func (p *jsonPathParser) quoted() (string, error) {
+	quote := p.expr[p.pos]
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.expr); p.pos++ {
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.expr):
+			p.pos++
+			b.WriteByte(p.expr[p.pos])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *jsonPathParser) or() (*jsonPathFilter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpace(); p.consume("||"); p.skipSpace() {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathFilter{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *jsonPathParser) and() (*jsonPathFilter, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpace(); p.consume("&&"); p.skipSpace() {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathFilter{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *jsonPathParser) comparison() (*jsonPathFilter, error) {
+	p.skipSpace()
+	switch {
+	case p.consume("("):
+		filter, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return filter, nil
+	case p.consume("!"):
+		filter, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathFilter{op: "!", left: filter}, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return &jsonPathFilter{op: op, left: left, right: right}, nil
+		}
+	}
+
+	if !left.relative && !left.absolute {
+		return nil, p.errorf("expected a comparison")
+	}
+
+	return &jsonPathFilter{op: "exists", left: left}, nil
+}
+
+func (p *jsonPathParser) operand() (*jsonPathFilter, error) {
+	p.skipSpace()
+	switch {
+	case p.consume("@"):
+		path, err := p.segments()
+		return &jsonPathFilter{path: path, relative: true}, err
+	case p.consume("$"):
+		path, err := p.segments()
+		return &jsonPathFilter{path: path, absolute: true}, err
+	case strings.HasPrefix(p.expr[p.pos:], "'"), strings.HasPrefix(p.expr[p.pos:], `"`):
+		s, err := p.quoted()
+		return &jsonPathFilter{literal: s}, err
+	}
+
+	for literal, value := range map[string]any{"true": true, "false": false, "null": nil} {
+		if p.consume(literal) {
+			return &jsonPathFilter{literal: value}, nil
+		}
+	}
+
+	start := p.pos
+	for p.pos < len(p.expr) && strings.IndexByte("+-.0123456789eE", p.expr[p.pos]) >= 0 {
+		p.pos++
+	}
+	n, err := strconv.ParseFloat(p.expr[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("expected a value")
+	}
+
+	return &jsonPathFilter{literal: n}, nil
+}
+
+// eval returns the values selected by the path in document order.
+func (path jsonPath) eval(doc any) []any {
+	return path.evalFrom(doc, doc)
+}
+
+func (path jsonPath) evalFrom(root, current any) []any {
+	nodes := []any{current}
+	for _, segment := range path {
+		var selected []any
+		for _, node := range nodes {
+			targets := []any{node}
+			if segment.recursive {
+				targets = descendants(node)
+			}
+			for _, target := range targets {
+				for _, selector := range segment.selectors {
+					selected = append(selected, selector.apply(root, target)...)
+				}
+			}
+		}
+		nodes = selected
+	}
+
+	return nodes
+}
+
+// descendants returns node and all values nested in it.
+func descendants(node any) []any {
+	nodes := []any{node}
+	for _, child := range children(node) {
+		nodes = append(nodes, descendants(child)...)
+	}
+
+	return nodes
+}
+
+// children returns the elements of an array or the values of an object,
+// sorted by key.
+func children(node any) []any {
+	switch v := node.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		var values []any
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, v[key])
+		}
+		return values
+	}
+
+	return nil
+}
+
+func (s jsonPathSelector) apply(root, node any) []any {
+	switch {
+	case s.wildcard:
+		return children(node)
+	case s.name != nil:
+		if object, ok := node.(map[string]any); ok {
+			if value, ok := object[*s.name]; ok {
+				return []any{value}
+			}
+		}
+	case s.index != nil:
+		if array, ok := node.([]any); ok {
+			index := *s.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return []any{array[index]}
+			}
+		}
+	case s.slice != nil:
+		if array, ok := node.([]any); ok {
+			return sliceArray(array, s.slice)
+		}
+	case s.filter != nil:
+		var selected []any
+		for _, child := range children(node) {
+			if s.filter.test(root, child) {
+				selected = append(selected, child)
+			}
+		}
+		return selected
+	}
+
+	return nil
+}
+
+func sliceArray(array []any, bounds *[3]*int) []any {
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return nil
+	}
+
+	normalize := func(i int) int {
+		if i < 0 {
+			i += len(array)
+		}
+		return i
+	}
+	start, end := 0, len(array)
+	if step < 0 {
+		start, end = len(array)-1, -len(array)-1
+	}
+	if bounds[0] != nil {
+		start = normalize(*bounds[0])
+	}
+	if bounds[1] != nil {
+		end = normalize(*bounds[1])
+	}
+
+	var selected []any
+	if step > 0 {
+		for i := max(start, 0); i < min(end, len(array)); i += step {
+			selected = append(selected, array[i])
+		}
+	} else {
+		for i := min(start, len(array)-1); i > max(end, -1); i += step {
+			selected = append(selected, array[i])
+		}
+	}
+
+	return selected
+}
+
+func (f *jsonPathFilter) test(root, current any) bool {
+	switch f.op {
+	case "||":
+		return f.left.test(root, current) || f.right.test(root, current)
+	case "&&":
+		return f.left.test(root, current) && f.right.test(root, current)
+	case "!":
+		return !f.left.test(root, current)
+	case "exists":
+		return len(f.left.values(root, current)) > 0
+	}
+
+	left, right := f.left.values(root, current), f.right.values(root, current)
+	// Comparisons need a single value on both sides
+	if len(left) != 1 || len(right) != 1 {
+		return f.op == "!=" && len(left) != len(right)
+	}
+
+	return compareJSON(left[0], right[0], f.op)
+}
+
+func (f *jsonPathFilter) values(root, current any) []any {
+	switch {
+	case f.relative:
+		return f.path.evalFrom(root, current)
+	case f.absolute:
+		return f.path.evalFrom(root, root)
+	}
+
+	return []any{f.literal}
+}
+
+// compareJSON compares two decoded json values with a comparison operator.
+// Values of different types are only unequal.
+func compareJSON(a, b any, op string) bool {
+	var order int
+	comparable := true
+	if x, ok := jsonNumber(a); ok {
+		y, ok := jsonNumber(b)
+		if !ok {
+			return op == "!="
+		}
+		order = cmp.Compare(x, y)
+	} else if x, ok := a.(string); ok {
+		y, ok := b.(string)
+		if !ok {
+			return op == "!="
+		}
+		order = cmp.Compare(x, y)
+	} else {
+		comparable = false
+		if !reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b)) {
+			order = 1
+		}
+	}
+
+	switch op {
+	case "==":
+		return order == 0
+	case "!=":
+		return order != 0
+	}
+	if !comparable {
+		return false
+	}
+
+	switch op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	}
+
+	return false
+}
+
+func jsonNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+// normalizeJSON converts the json.Numbers in v to float64 so equal values
+// compare equal regardless of how they were written.
+func normalizeJSON(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		normalized := make([]any, len(v))
+		for i, value := range v {
+			normalized[i] = normalizeJSON(value)
+		}
+		return normalized
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for key, value := range v {
+			normalized[key] = normalizeJSON(value)
+		}
+		return normalized
+	}
+
+	return v
+}
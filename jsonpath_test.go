@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const jsonPathStore = `{
+	"store": {
+		"book": [
+			{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+			{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+			{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+			{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"weird key": {"a.b": 1, "it's": 2}
+}`
+
+func TestQueryJSONPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// Names
+		{"$", ""},
+		{"$.store.bicycle.color", `["red"]`},
+		{"$.store.missing", `[]`},
+		{"$.store.*.color", `["red"]`},
+		// Quoted keys
+		{"$['weird key']['a.b']", `[1]`},
+		{`$["weird key"]["it's"]`, `[2]`},
+		{`$['weird key']['it\'s']`, `[2]`},
+		// Indexes
+		{"$.store.book[0].title", `["Sayings of the Century"]`},
+		{"$.store.book[-1].title", `["The Lord of the Rings"]`},
+		{"$.store.book[-5].title", `[]`},
+		{"$.store.book[4]", `[]`},
+		// Slices
+		{"$.store.book[1:3].price", `[12.99,8.99]`},
+		{"$.store.book[:2].price", `[8.95,12.99]`},
+		{"$.store.book[-2:].price", `[8.99,22.99]`},
+		{"$.store.book[::2].price", `[8.95,8.99]`},
+		{"$.store.book[::-1].price", `[22.99,8.99,12.99,8.95]`},
+		{"$.store.book[2:0:-1].price", `[8.99,12.99]`},
+		{"$.store.book[::0].price", `[]`},
+		// Unions
+		{"$.store.book[0,-1].author", `["Nigel Rees","J. R. R. Tolkien"]`},
+		{"$.store.bicycle['color','price']", `["red",19.95]`},
+		// Recursive descent, objects in key order
+		{"$..isbn", `["0-553-21311-3","0-395-19395-8"]`},
+		{"$.store..price", `[19.95,8.95,12.99,8.99,22.99]`},
+		{"$..book[1].author", `["Evelyn Waugh"]`},
+		// Filters
+		{"$..book[?(@.price < 10)].title", `["Sayings of the Century","Moby Dick"]`},
+		{"$..book[?(@.isbn)].title", `["Moby Dick","The Lord of the Rings"]`},
+		{"$..book[?(!@.isbn)].title", `["Sayings of the Century","Sword of Honour"]`},
+		{"$..book[?(@.category == 'fiction' && @.price < 20)].title", `["Sword of Honour","Moby Dick"]`},
+		{"$..book[?(@.price < 9 || @.price > 20)].price", `[8.95,8.99,22.99]`},
+		{"$..book[?(!(@.category == 'fiction') || @.isbn && @.price > 20)].title", `["Sayings of the Century","The Lord of the Rings"]`},
+		{"$..book[?(@.price == $.store.book[0].price)].author", `["Nigel Rees"]`},
+		{"$..book[?(@.price != 8.95)].price", `[12.99,8.99,22.99]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			results, err := queryJSONPath(tt.expr, jsonPathStore)
+			if err != nil {
+				t.Fatalf("queryJSONPath() error = %v", err)
+			}
+			if tt.want == "" {
+				if len(results) != 1 {
+					t.Fatalf("queryJSONPath() = %d results, want the document", len(results))
+				}
+				return
+			}
+
+			got, err := json.Marshal(append([]any{}, results...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("queryJSONPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		"store",
+		"$.",
+		"$[",
+		"$['a'",
+		"$[1:2:3:4]",
+		"$[?(@.a <)]",
+		"$[?(@.a == 1]",
+		"$.a b",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseJSONPath(expr); err == nil {
+				t.Errorf("parseJSONPath(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
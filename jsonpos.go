@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// jsonKeyPathAt returns the keys and array indices leading to the deepest
+// value of data whose source span contains the byte offset. The span of an
+// object member starts at its key and ends after its value and the comma
+// that follows it.
+func jsonKeyPathAt(data []byte, offset int) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var found []any
+	contains := func(start, end int64, path []any) {
+		start = skipJSONSpace(data, start, true)
+		if end = skipJSONSpace(data, end, false); end < int64(len(data)) && data[end] == ',' {
+			end++
+		}
+		if int64(offset) >= start && int64(offset) < end && len(path) > len(found) {
+			found = path
+		}
+	}
+
+	var walk func(path []any) error
+	walk = func(path []any) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				start := dec.InputOffset()
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return fmt.Errorf("unexpected object key %v", keyTok)
+				}
+				memberPath := append(slices.Clone(path), key)
+				if err := walk(memberPath); err != nil {
+					return err
+				}
+				contains(start, dec.InputOffset(), memberPath)
+			}
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				start := dec.InputOffset()
+				elementPath := append(slices.Clone(path), i)
+				if err := walk(elementPath); err != nil {
+					return err
+				}
+				contains(start, dec.InputOffset(), elementPath)
+			}
+		default:
+			return nil
+		}
+
+		// Consume the closing delimiter
+		_, err = dec.Token()
+
+		return err
+	}
+
+	if err := walk(nil); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// skipJSONSpace returns the offset of the first byte from offset on that is
+// not whitespace, or a comma when commas is set.
+func skipJSONSpace(data []byte, offset int64, commas bool) int64 {
+	for ; offset < int64(len(data)); offset++ {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n':
+		case ',':
+			if !commas {
+				return offset
+			}
+		default:
+			return offset
+		}
+	}
+
+	return offset
+}
+
+// cursorOffset returns the byte offset in value of the rune at row and col,
+// moved onto the text of that line when the cursor sits in its indentation
+// or after its end.
+func cursorOffset(value string, row, col int) (int, error) {
+	lines := strings.Split(value, "\n")
+	if row >= len(lines) {
+		return 0, errors.New("cursor is outside of the collection")
+	}
+
+	line := []rune(lines[row])
+	indent := len(line) - len([]rune(strings.TrimLeft(lines[row], " \t")))
+	end := len([]rune(strings.TrimRight(lines[row], " \t")))
+	col = max(min(col, end-1), indent, 0)
+
+	offset := len(string(line[:min(col, len(line))]))
+	for _, l := range lines[:row] {
+		offset += len(l) + 1
+	}
+
+	return offset, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONKeyPathAt(t *testing.T) {
+	// | marks the cursor, and is removed before the lookup
+	tests := []struct {
+		name string
+		data string
+		want []any
+	}{
+		{"compact first key", `{"|a": 1, "b": 2}`, []any{"a"}},
+		{"compact second key", `{"a": 1, "|b": 2}`, []any{"b"}},
+		{"compact second value", `{"a": 1, "b": |2}`, []any{"b"}},
+		{"comma after value", `{"a": 1|, "b": 2}`, []any{"a"}},
+		{"space between members", `{"a": 1,| "b": 2}`, nil},
+		{"opening brace", `|{"a": 1}`, nil},
+		{"nested object", `{"a": {"b": 1, "|c": {"d": true}}}`, []any{"a", "c"}},
+		{"nested object value", `{"a": {"b": 1, "c": {"d": |true}}}`, []any{"a", "c", "d"}},
+		{"closing brace of nested object", `{"a": {"b": 1|}, "c": 2}`, []any{"a"}},
+		{"array element", `{"requests": [{"url": "x"}, |{"url": "y"}]}`, []any{"requests", 1}},
+		{"array element key", `{"requests": [{"url": "x"}, {"|url": "y"}]}`, []any{"requests", 1, "url"}},
+		{"nested arrays", `[[1, 2], [3, |4]]`, []any{1, 1}},
+		{"indented", "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": |2\n  }\n}", []any{"b", "c"}},
+		{"indented closing brace", "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": 2\n  |}\n}", []any{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset := strings.Index(tt.data, "|")
+			got, err := jsonKeyPathAt([]byte(strings.Replace(tt.data, "|", "", 1)), offset)
+			if err != nil {
+				t.Fatalf("jsonKeyPathAt() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonKeyPathAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := jsonKeyPathAt([]byte(`{"a": `), 2); err == nil {
+		t.Errorf("jsonKeyPathAt() of invalid json succeeded")
+	}
+}
+
+func TestCursorOffset(t *testing.T) {
+	value := "{\n  \"a\": \"é\",\n  \"b\": 2\n}"
+	tests := []struct {
+		row, col int
+		want     int
+	}{
+		{0, 0, 0},
+		{1, 0, 4},
+		{1, 3, 5},
+		{1, 8, 10},
+		{1, 10, 13},
+		{1, 40, 13},
+		{2, 2, 17},
+		{3, 0, 24},
+	}
+	for _, tt := range tests {
+		got, err := cursorOffset(value, tt.row, tt.col)
+		if err != nil || got != tt.want {
+			t.Errorf("cursorOffset(%d, %d) = %d, %v, want %d", tt.row, tt.col, got, err, tt.want)
+		}
+	}
+
+	if _, err := cursorOffset(value, 4, 0); err == nil {
+		t.Errorf("cursorOffset() outside of the value succeeded")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonNode is a value in a json document. Scalars keep their json text,
+// objects and arrays their members in document order.
+type jsonNode struct {
+	parent   *jsonNode
+	key      string
+	index    int
+	delim    json.Delim
+	value    string
+	children []*jsonNode
+	expanded bool
+}
+
+// jsonTree is the tree view of a json response body, of which the rows of
+// the expanded nodes are shown.
+type jsonTree struct {
+	root     *jsonNode
+	selected int
+}
+
+func parseJSONTree(body string) (*jsonTree, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+
+	root, err := parseJSONNode(dec, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the json value")
+	}
+	root.expanded = true
+
+	return &jsonTree{root: root}, nil
+}
+
+func parseJSONNode(dec *json.Decoder, parent *jsonNode) (*jsonNode, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &jsonNode{parent: parent, index: -1}
+	switch t := token.(type) {
+	case json.Delim:
+		n.delim = t
+		for dec.More() {
+			key := ""
+			if t == '{' {
+				if token, err = dec.Token(); err != nil {
+					return nil, err
+				}
+				key = token.(string)
+			}
+
+			child, err := parseJSONNode(dec, n)
+			if err != nil {
+				return nil, err
+			}
+			child.key = key
+			if t == '[' {
+				child.index = len(n.children)
+			}
+			n.children = append(n.children, child)
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.value = jsonString(t)
+	case nil:
+		n.value = "null"
+	default:
+		n.value = fmt.Sprint(t)
+	}
+
+	return n, nil
+}
+
+func (n *jsonNode) isContainer() bool {
+	return n.delim != 0
+}
+
+// path returns the JSONPath of n, like $.users[0]['first name'].
+func (n *jsonNode) path() string {
+	if n.parent == nil {
+		return "$"
+	}
+
+	switch {
+	case n.index >= 0:
+		return fmt.Sprintf("%s[%d]", n.parent.path(), n.index)
+	case jsonPathIdentifier.MatchString(n.key):
+		return n.parent.path() + "." + n.key
+	}
+
+	quoted := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(n.key)
+	return n.parent.path() + "['" + quoted + "']"
+}
+
+// json returns the indented json text of n.
+func (n *jsonNode) json() string {
+	var b bytes.Buffer
+	n.writeJSON(&b)
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b.Bytes(), "", "  "); err != nil {
+		return b.String()
+	}
+
+	return indented.String()
+}
+
+func (n *jsonNode) writeJSON(b *bytes.Buffer) {
+	if !n.isContainer() {
+		b.WriteString(n.value)
+		return
+	}
+
+	b.WriteRune(rune(n.delim))
+	for i, child := range n.children {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if n.delim == '{' {
+			b.WriteString(jsonString(child.key) + ":")
+		}
+		child.writeJSON(b)
+	}
+	if n.delim == '{' {
+		b.WriteByte('}')
+	} else {
+		b.WriteByte(']')
+	}
+}
+
+// summary describes a container by its number of children.
+func (n *jsonNode) summary() string {
+	if n.delim == '{' {
+		return fmt.Sprintf("{%d %s}", len(n.children), plural(len(n.children), "key", "keys"))
+	}
+
+	return fmt.Sprintf("[%d %s]", len(n.children), plural(len(n.children), "item", "items"))
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+
+	return plural
+}
+
+// rows returns the visible nodes, those whose ancestors are all expanded.
+func (t *jsonTree) rows() []*jsonNode {
+	var rows []*jsonNode
+	var walk func(n *jsonNode)
+	walk = func(n *jsonNode) {
+		rows = append(rows, n)
+		if n.expanded {
+			for _, child := range n.children {
+				walk(child)
+			}
+		}
+	}
+	walk(t.root)
+
+	return rows
+}
+
+func (t *jsonTree) selectedNode() *jsonNode {
+	rows := t.rows()
+	t.selected = max(min(t.selected, len(rows)-1), 0)
+
+	return rows[t.selected]
+}
+
+func (t *jsonTree) move(delta int) {
+	t.selected += delta
+	t.selectedNode()
+}
+
+// expand opens the selected node, or moves to its first child when it is
+// already open.
+func (t *jsonTree) expand() {
+	n := t.selectedNode()
+	switch {
+	case !n.isContainer():
+	case !n.expanded:
+		n.expanded = true
+	case len(n.children) > 0:
+		t.selected++
+	}
+}
+
+// collapse closes the selected node, or moves to its parent when it is
+// already closed.
+func (t *jsonTree) collapse() {
+	n := t.selectedNode()
+	if n.isContainer() && n.expanded {
+		n.expanded = false
+		return
+	}
+
+	if n.parent != nil {
+		t.selectNode(n.parent)
+	}
+}
+
+func (t *jsonTree) toggle() {
+	if n := t.selectedNode(); n.isContainer() {
+		n.expanded = !n.expanded
+	}
+}
+
+func (t *jsonTree) selectNode(n *jsonNode) {
+	for i, row := range t.rows() {
+		if row == n {
+			t.selected = i
+			return
+		}
+	}
+}
+
+func (t *jsonTree) depth(n *jsonNode) int {
+	depth := 0
+	for p := n.parent; p != nil; p = p.parent {
+		depth++
+	}
+
+	return depth
+}
+
+func (t *jsonTree) String() string {
+	var b strings.Builder
+	for i, n := range t.rows() {
+		prefix := "  "
+		if i == t.selected {
+			prefix = "> "
+		}
+		b.WriteString(prefix + strings.Repeat("  ", t.depth(n)))
+
+		switch {
+		case !n.isContainer():
+			b.WriteString("  ")
+		case n.expanded:
+			b.WriteString("▾ ")
+		default:
+			b.WriteString("▸ ")
+		}
+
+		switch {
+		case n.parent == nil:
+		case n.index >= 0:
+			fmt.Fprintf(&b, "%d: ", n.index)
+		default:
+			b.WriteString(jsonString(n.key) + ": ")
+		}
+
+		if n.isContainer() {
+			b.WriteString(n.summary())
+		} else {
+			b.WriteString(n.value)
+		}
+		b.WriteRune('\n')
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+)
+
+const jsonTreeTestBody = `{"users": [{"id": 1, "first name": "Ada", "it's": null}], "count": 1.50, "next": {}}`
+
+func TestParseJSONTree(t *testing.T) {
+	tree, err := parseJSONTree(jsonTreeTestBody)
+	if err != nil {
+		t.Fatalf("parseJSONTree() error = %v", err)
+	}
+
+	want := `> ▾ {3 keys}
+    ▸ "users": [1 item]
+      "count": 1.50
+    ▸ "next": {0 keys}`
+	if got := tree.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	for _, body := range []string{`{"a": `, `{"a": 1} {"b": 2}`, `not json`} {
+		if _, err := parseJSONTree(body); err == nil {
+			t.Errorf("parseJSONTree(%q) succeeded", body)
+		}
+	}
+}
+
+func TestJSONTreeNavigation(t *testing.T) {
+	tree, err := parseJSONTree(jsonTreeTestBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree.move(1)
+	tree.expand()
+	if n := tree.selectedNode(); n.key != "users" || !n.expanded {
+		t.Fatalf("expand() selected %q, expanded %v", n.key, n.expanded)
+	}
+	// Expanding an open node moves into it
+	tree.expand()
+	tree.expand()
+	tree.expand()
+	if n := tree.selectedNode(); n.path() != "$.users[0].id" {
+		t.Errorf("selected %s, want $.users[0].id", n.path())
+	}
+
+	want := `  ▾ {3 keys}
+    ▾ "users": [1 item]
+      ▾ 0: {3 keys}
+>         "id": 1
+          "first name": "Ada"
+          "it's": null
+      "count": 1.50
+    ▸ "next": {0 keys}`
+	if got := tree.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Collapsing a scalar moves to its parent, collapsing that closes it
+	tree.collapse()
+	if n := tree.selectedNode(); n.path() != "$.users[0]" {
+		t.Errorf("collapse() selected %s, want $.users[0]", n.path())
+	}
+	tree.collapse()
+	if n := tree.selectedNode(); n.expanded || len(tree.rows()) != 5 {
+		t.Errorf("collapse() left %s expanded with %d rows", n.path(), len(tree.rows()))
+	}
+
+	tree.toggle()
+	if len(tree.rows()) != 8 {
+		t.Errorf("toggle() shows %d rows, want 8", len(tree.rows()))
+	}
+
+	// The selection stays on the rows
+	tree.move(100)
+	if n := tree.selectedNode(); n.key != "next" {
+		t.Errorf("move(100) selected %q, want next", n.key)
+	}
+	tree.move(-100)
+	if n := tree.selectedNode(); n.parent != nil {
+		t.Errorf("move(-100) selected %s, want the root", n.path())
+	}
+	tree.toggle()
+	if len(tree.rows()) != 1 {
+		t.Errorf("toggle() of the root shows %d rows, want 1", len(tree.rows()))
+	}
+}
+
+func TestJSONNodeCopy(t *testing.T) {
+	tree, err := parseJSONTree(jsonTreeTestBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := tree.root.children[0]
+	user := users.children[0]
+
+	tests := []struct {
+		node     *jsonNode
+		wantPath string
+		wantJSON string
+	}{
+		{tree.root, "$", "{\n  \"users\": [\n    {\n      \"id\": 1,\n      \"first name\": \"Ada\",\n      \"it's\": null\n    }\n  ],\n  \"count\": 1.50,\n  \"next\": {}\n}"},
+		{users, "$.users", "[\n  {\n    \"id\": 1,\n    \"first name\": \"Ada\",\n    \"it's\": null\n  }\n]"},
+		{user.children[0], "$.users[0].id", "1"},
+		{user.children[1], "$.users[0]['first name']", `"Ada"`},
+		{user.children[2], `$.users[0]['it\'s']`, "null"},
+		{tree.root.children[2], "$.next", "{}"},
+	}
+	for _, tt := range tests {
+		if got := tt.node.path(); got != tt.wantPath {
+			t.Errorf("path() = %q, want %q", got, tt.wantPath)
+		}
+		if got := tt.node.json(); got != tt.wantJSON {
+			t.Errorf("json() of %s = %q, want %q", tt.wantPath, got, tt.wantJSON)
+		}
+	}
+}
+
+func TestIsTreeActive(t *testing.T) {
+	m := newTestModel(t)
+	m.showResponse(responseMsg{statusCode: 200, responseBody: jsonTreeTestBody})
+	m.treeView = true
+	m.renderResponseBody()
+	if !m.isTreeActive() {
+		t.Fatal("isTreeActive() = false with the tree focused")
+	}
+
+	m.currentFocus = FocusInput
+	if m.isTreeActive() {
+		t.Error("isTreeActive() = true while the url input has the focus")
+	}
+
+	m.currentFocus = FocusResponseView
+	m.activeTab = TabResponseHeaders
+	if m.isTreeActive() {
+		t.Error("isTreeActive() = true on another tab")
+	}
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"net/http"
-	"net/url"
 	"os"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
@@ -19,6 +21,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 type (
@@ -33,10 +36,20 @@ const (
 
 const (
 	TabCollection Tab = iota
+	TabHistory
+	TabEnvironment
 	TabRequestHeaders
 	TabRequestBody
+	TabAuth
+	TabScripts
+	TabSettings
+	TabCookies
 	TabResponseBody
 	TabResponseHeaders
+	TabTests
+	TabTimeline
+	TabCode
+	TabWarnings
 )
 
 const (
@@ -58,10 +71,11 @@ var (
 	windowStyle         = lipgloss.NewStyle().BorderForeground(nonHighlightColor).Align(lipgloss.Center).Border(lipgloss.NormalBorder()).UnsetBorderTop()
 	spinnerStyle        = lipgloss.NewStyle().Foreground(highlightColor)
 	statusCodeViewStyle = lipgloss.NewStyle().Background(lipgloss.CompleteColor{TrueColor: "#21FF4E"}).Foreground(lipgloss.CompleteColor{TrueColor: "#000000"})
+	errorStyle          = lipgloss.NewStyle().Foreground(lipgloss.CompleteColor{TrueColor: "#DA4939"})
 )
 
 type keymap = struct {
-	nextView, prevView, nextTab, prevTab, left, right, up, down, j, k, l, h, paste, run, addCollection, extractCollection, quit key.Binding
+	nextView, prevView, nextTab, prevTab, left, right, up, down, j, k, l, h, paste, run, cancel, addCollection, extractCollection, toggleRaw, toggleTree, toggleNode, copyPath, search, nextMatch, prevMatch, openHistory, rerun, filter, save, nextCollection, switchEnvironment, importCollection, exportCollection, pasteCurl, copyCurl, nextLanguage, copy, quit key.Binding
 }
 
 type model struct {
@@ -71,7 +85,12 @@ type model struct {
 	responseView   viewport.Model
 	requestHeaders textarea.Model
 	requestBody    textarea.Model
+	auth           textarea.Model
+	scripts        textarea.Model
 	collection     textarea.Model
+	environment    textarea.Model
+	clientSettings textarea.Model
+	cookies        textarea.Model
 	help           help.Model
 
 	activeTab    Tab
@@ -90,9 +109,78 @@ type model struct {
 	responseHeaders    string
 	tabs               []string
 	tabContent         []string
-	collectionMap      map[string]any
-
-	testExtract string
+	activeCollection   *Collection
+	collectionFile     string
+	collectionFiles    []string
+	activeEnvironment  *Environment
+	vault              *Vault
+	history            []HistoryEntry
+	historyFilter      string
+	historySelected    int
+
+	// variables are set by scripts while no environment is active
+	variables map[string]string
+
+	// inflight is the history entry of the request being sent
+	inflight      *HistoryEntry
+	requestID     int
+	cancelRequest context.CancelFunc
+	cancelled     bool
+
+	globalSettings Settings
+	// requestSettings override the global settings for the current request
+	requestSettings *Settings
+	jar             *cookieJar
+	transports      *transportCache
+
+	// requestAuth is the auth of the current request, without it the request
+	// inherits the auth of the collection and the folders on requestPath
+	requestAuth *Auth
+	requestPath []any
+	tokens      *tokenCache
+	// requestTests are the assertions checked on the responses of the
+	// current request
+	requestTests []string
+	// requestCaptures set variables from the responses of the current
+	// request, like token = $.access_token
+	requestCaptures []string
+	// requestScripts run before the current request is sent and after its
+	// response arrives, preRequestResult is what the last pre-request script
+	// did
+	requestScripts   *Scripts
+	preRequestResult *scriptResult
+
+	// rawResponse shows the response body as received instead of formatted
+	rawResponse bool
+	// highlighted is the last formatted response body, nil while it runs
+	highlighted *highlightMsg
+	highlightID int
+	// treeView shows json response bodies as a collapsible jsonTree
+	treeView bool
+	jsonTree *jsonTree
+	// bodyFilter is the jq or JSONPath expression applied to the response body,
+	// filtered is its result, nil while it runs
+	bodyFilter         string
+	previousBodyFilter string
+	filtered           *filterMsg
+	filterID           int
+	cancelFilter       context.CancelFunc
+
+	// search is looked up in the response body and headers, searchIndex is
+	// the current one of its matches
+	search         string
+	searchRegex    bool
+	searchCase     bool
+	searchIndex    int
+	searchCache    *searchCache
+	previousSearch string
+	// searchLine is the line the search prompt was opened at
+	searchLine int
+
+	statusMessage string
+	prompt        textinput.Model
+	promptAction  PromptAction
+	snippetIndex  int
 }
 
 func (m model) Init() tea.Cmd {
@@ -104,47 +192,97 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case responseMsg:
-		m.startSpinner = false
-		m.err = nil
-		m.currentFocus = FocusResponseView
-		m.statusCode = msg.statusCode
-		m.activeTab = TabResponseBody
-		m.responseBody = msg.responseBody
-		m.responseHeaders = msg.responseHeaders
-		m.responseTime = msg.responseTime
-		if len(m.tabContent) > 0 {
-			m.tabContent[TabResponseBody] = m.responseBody
-			m.tabContent[TabResponseHeaders] = m.responseHeaders
-			m.responseView.SetContent(m.tabContent[m.activeTab])
+		if msg.requestID != m.requestID || !m.startSpinner {
+			// A response of a cancelled or superseded request
+			break
 		}
-
-		if m.statusCode > 0 {
-			if m.statusCode < 300 {
-				statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#21FF4E"})
-			}
-
-			if m.statusCode > 299 && m.statusCode < 400 {
-				statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#FFC66D"})
+		m.finishRequest()
+		m.err = nil
+		sent := m.inflight
+		if m.inflight != nil {
+			m.inflight.setResponse(msg)
+			if err := m.recordHistory(); err != nil {
+				m.err = err
 			}
+		}
 
-			if m.statusCode > 399 {
-				statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#DA4939"})
-			}
-			statusMsg := fmt.Sprintf("%d %s", m.statusCode, http.StatusText(m.statusCode))
-			padding := (m.statusCodeView.Width - len(statusMsg)) / 2
-			m.statusCodeView.SetContent(fmt.Sprintf("%s%s", strings.Repeat(" ", padding), statusMsg))
-			m.statusCodeView.Style = statusCodeViewStyle
+		cmds = append(cmds, m.showResponse(msg))
+		if err := m.captureVariables(msg); err != nil {
+			m.err = err
+		}
+		cmds = append(cmds, m.runTests(msg, sent))
+		if err := m.storeCookies(); err != nil {
+			m.err = err
 		}
 
 		for i := range m.inputs {
 			// Remove focus from inputs
 			m.inputs[i].Blur()
 		}
+	case filterMsg:
+		if msg.filterID != m.filterID {
+			// The result of a superseded filter
+			break
+		}
+		m.cancelFilter()
+		m.cancelFilter = nil
+		m.filtered = &msg
+		m.jsonTree = nil
+		cmds = append(cmds, m.renderResponseBody())
+		if m.activeTab == TabResponseBody {
+			m.responseView.SetContent(m.tabContent[TabResponseBody])
+			m.responseView.GotoTop()
+		}
+	case highlightMsg:
+		if msg.highlightID != m.highlightID {
+			// The formatting of a body no longer shown
+			break
+		}
+		m.highlighted = &msg
+		m.renderResponseBody()
+		if m.activeTab == TabResponseBody {
+			m.responseView.SetContent(m.tabContent[TabResponseBody])
+		}
+	case preRequestMsg:
+		if msg.requestID != m.requestID || !m.startSpinner {
+			// The pre-request script of a cancelled or superseded request
+			break
+		}
+		m.finishRequest()
+		m.preRequestResult = msg.result
+		if err := m.setVariables(msg.result.variables); err != nil {
+			m.err = err
+			break
+		}
+		cmds = append(cmds, m.send(msg.draft))
+	case postResponseMsg:
+		if msg.requestID != m.requestID {
+			break
+		}
+		if err := m.setVariables(msg.variables); err != nil && msg.err == nil {
+			msg.err = err
+		}
+		m.reportTests(msg.tests, msg.logs)
+		if m.activeTab == TabTests {
+			m.responseView.SetContent(m.tabContent[TabTests])
+		}
+		if msg.err != nil {
+			m.err = msg.err
+		}
 	case errMsg:
-		m.startSpinner = false
-		m.statusCode = 0
-		m.responseTime = 0
-		m.responseBody = ""
+		if msg.requestID != 0 {
+			if msg.requestID != m.requestID || !m.startSpinner {
+				break
+			}
+			if m.inflight != nil {
+				m.inflight.Error = m.maskSecrets(msg.Error())
+				_ = m.recordHistory()
+			}
+			m.finishRequest()
+			m.statusCode = 0
+			m.responseTime = 0
+			m.responseBody = ""
+		}
 		m.err = msg
 		m.responseView.SetContent(m.tabContent[m.activeTab])
 
@@ -158,6 +296,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.responseViewWidth = msg.Width
 		m.responseViewHeight = msg.Height - paddingHeight
+		m.help.Width = msg.Width
 
 		windowStyle = windowStyle.Width(m.responseViewWidth).Height(m.responseViewHeight)
 
@@ -168,69 +307,110 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.responseView.Width = m.responseViewWidth
 		m.responseView.Height = m.responseViewHeight
 
-		m.requestHeaders.SetWidth(m.responseViewWidth)
-		m.requestHeaders.SetHeight(m.responseViewHeight)
-
-		m.collection.SetWidth(m.responseViewWidth)
-		m.collection.SetHeight(m.responseViewHeight)
-
-		m.requestBody.SetWidth(m.responseViewWidth)
-		m.requestBody.SetHeight(m.responseViewHeight)
+		for _, editor := range m.editors() {
+			editor.SetWidth(m.responseViewWidth)
+			editor.SetHeight(m.responseViewHeight)
+		}
 
 		m.responseView.Style = windowStyle
 
 		m.updateFocusView()
 
 	case tea.KeyMsg:
+		if m.promptAction != PromptNone {
+			return m.updatePrompt(msg)
+		}
+
 		switch {
 		case key.Matches(msg, m.keymap.left):
 			m.updateCursorPos(m.cursorPos - 1)
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.collapse()
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollLeft(1)
 			}
 		case key.Matches(msg, m.keymap.right):
 			m.updateCursorPos(m.cursorPos + 1)
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.expand()
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollLeft(1)
 			}
 		case key.Matches(msg, m.keymap.h):
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.collapse()
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollLeft(1)
 			}
 		case key.Matches(msg, m.keymap.j):
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.move(1)
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollDown(1)
 			}
 		case key.Matches(msg, m.keymap.k):
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.move(-1)
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollUp(1)
 			}
 		case key.Matches(msg, m.keymap.l):
-			if m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders {
+			if m.isTreeActive() {
+				m.jsonTree.expand()
+				m.renderTree()
+			} else if m.isViewportTab() {
 				m.responseView.ScrollRight(1)
 			}
 		case key.Matches(msg, m.keymap.up):
-			switch m.activeTab {
-			case TabCollection:
-				m.collection.CursorUp()
-			case TabRequestHeaders:
-				m.requestHeaders.CursorUp()
-			case TabRequestBody:
-				m.requestBody.CursorUp()
-			case TabResponseBody, TabResponseHeaders:
+			if editor := m.editor(m.activeTab); editor != nil {
+				editor.CursorUp()
+			} else if m.activeTab == TabHistory {
+				m.selectHistory(m.historySelected - 1)
+			} else if m.isTreeActive() {
+				m.jsonTree.move(-1)
+				m.renderTree()
+			} else {
 				m.responseView.ScrollUp(1)
 			}
 		case key.Matches(msg, m.keymap.down):
-			switch m.activeTab {
-			case TabCollection:
-				m.collection.CursorDown()
-			case TabRequestHeaders:
-				m.requestHeaders.CursorDown()
-			case TabRequestBody:
-				m.requestBody.CursorDown()
-			case TabResponseBody, TabResponseHeaders:
+			if editor := m.editor(m.activeTab); editor != nil {
+				editor.CursorDown()
+			} else if m.activeTab == TabHistory {
+				m.selectHistory(m.historySelected + 1)
+			} else if m.isTreeActive() {
+				m.jsonTree.move(1)
+				m.renderTree()
+			} else {
 				m.responseView.ScrollDown(1)
 			}
+		case key.Matches(msg, m.keymap.toggleNode):
+			if m.isTreeActive() {
+				m.jsonTree.toggle()
+				m.renderTree()
+			}
+		case key.Matches(msg, m.keymap.search):
+			if m.currentFocus != FocusResponseView || !m.isSearchTab() {
+				break
+			}
+			cmds = append(cmds, m.openPrompt(PromptSearch, "", "text to find, alt+c to match case, alt+r for a regular expression"))
+			m.searchPrompt()
+			m.prompt.SetValue(m.search)
+			m.previousSearch = m.search
+			m.searchLine = m.responseView.YOffset
+		case key.Matches(msg, m.keymap.nextMatch), key.Matches(msg, m.keymap.prevMatch):
+			if m.currentFocus != FocusResponseView || !m.isSearchTab() {
+				break
+			}
+			if key.Matches(msg, m.keymap.nextMatch) {
+				m.nextMatch(1)
+			} else {
+				m.nextMatch(-1)
+			}
 		case key.Matches(msg, m.keymap.paste):
 			cb, err := clipboard.ReadAll()
 			if err != nil {
@@ -248,13 +428,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.cursorPos += len(cb)
 			case FocusResponseView:
-				switch m.activeTab {
-				case TabCollection:
-					m.collection.InsertString(cb)
-				case TabRequestHeaders:
-					m.requestHeaders.InsertString(cb)
-				case TabRequestBody:
-					m.requestBody.InsertString(cb)
+				if editor := m.editor(m.activeTab); editor != nil {
+					editor.InsertString(cb)
 				}
 			}
 		case key.Matches(msg, m.keymap.nextTab), key.Matches(msg, m.keymap.prevTab):
@@ -280,9 +455,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if i == m.focusInputIndex {
 						// Set focused state
 						cmds = append(cmds, m.inputs[i].Focus())
-						m.requestHeaders.Blur()
-						m.collection.Blur()
-						m.requestBody.Blur()
+						m.blurEditors()
 						m.inputs[i].PromptStyle = focusedStyle
 						m.inputs[i].TextStyle = focusedStyle
 						continue
@@ -306,103 +479,304 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.activeTab = Tab(len(m.tabs) - 1)
 				}
 
-				switch m.activeTab {
-				case TabCollection:
-					m.collection.Focus()
-					m.requestBody.Blur()
-					m.requestHeaders.Blur()
-				case TabRequestHeaders:
-					m.requestHeaders.Focus()
-					m.requestBody.Blur()
-					m.collection.Blur()
-				case TabRequestBody:
-					m.requestBody.Focus()
-					m.requestHeaders.Blur()
-					m.collection.Blur()
-				default:
-					m.requestHeaders.Blur()
-					m.collection.Blur()
-					m.requestBody.Blur()
+				m.blurEditors()
+				if editor := m.editor(m.activeTab); editor != nil {
+					editor.Focus()
+				} else {
+					if m.activeTab == TabCode {
+						m.updateSnippet()
+					}
 					m.responseView.SetContent(m.tabContent[m.activeTab])
+					if m.activeTab == TabHistory {
+						m.selectHistory(m.historySelected)
+					}
 				}
 
 			}
 		case key.Matches(msg, m.keymap.quit):
 			return m, tea.Quit
 		case key.Matches(msg, m.keymap.run):
-			m.startSpinner = true
-			m.responseTime = 0
-			inputUrl := m.inputs[0].Value()
-			method := m.inputs[1].Value()
-			headers := m.parseHeaders()
-			body := m.requestBody.Value()
-			cmds = append(cmds, m.spinner.Tick)
-			cmds = append(cmds, doRequest(inputUrl, method, headers, body))
+			cmds = append(cmds, m.run())
+		case key.Matches(msg, m.keymap.cancel):
+			if m.startSpinner {
+				m.cancel()
+			}
+		case key.Matches(msg, m.keymap.rerun):
+			if m.activeTab != TabHistory || m.selectedHistoryEntry() == nil {
+				break
+			}
+			cmds = append(cmds, m.openHistoryEntry(), m.run())
+		case key.Matches(msg, m.keymap.openHistory):
+			if m.activeTab != TabHistory || m.currentFocus != FocusResponseView {
+				break
+			}
+			cmds = append(cmds, m.openHistoryEntry())
+		case key.Matches(msg, m.keymap.filter):
+			switch m.activeTab {
+			case TabHistory:
+				cmds = append(cmds, m.openPrompt(PromptHistoryFilter, "Filter history: ", "text to match in the time, status, method or url"))
+				m.prompt.SetValue(m.historyFilter)
+			case TabResponseBody:
+				cmds = append(cmds, m.openPrompt(PromptBodyFilter, "Filter body: ", "jq program, or JSONPath starting with $"))
+				m.prompt.SetValue(m.bodyFilter)
+				m.previousBodyFilter = m.bodyFilter
+			}
 		case key.Matches(msg, m.keymap.addCollection):
-			inputUrl := m.inputs[0].Value()
-			method := m.inputs[1].Value()
-
-			parsedUrl, err := url.Parse(inputUrl)
+			var r *Request
+			var err error
+			if m.activeTab == TabHistory {
+				entry := m.selectedHistoryEntry()
+				if entry == nil {
+					break
+				}
+				r, err = newRequest(entry.Method, m.maskSecrets(entry.URL), entry.Headers, m.maskSecrets(entry.Body))
+			} else {
+				headers := m.rawHeaders()
+				for key, value := range headers {
+					headers[key] = m.maskSecrets(value)
+				}
+				r, err = newRequest(m.inputs[1].Value(), m.maskSecrets(m.inputs[0].Value()), headers, m.maskSecrets(m.requestBody.Value()))
+			}
 			if err != nil {
 				return m, func() tea.Msg {
 					return errMsg{err: err}
 				}
 			}
-			scheme := parsedUrl.Scheme
-			host := parsedUrl.Host
-			path := parsedUrl.Path
-			headers := m.parseHeaders()
 
-			if m.collectionMap == nil {
-				m.collectionMap = map[string]any{
-					"name":    "",
-					"scheme":  scheme,
-					"host":    host,
-					"headers": headers,
+			if err := m.addToCollection(r); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+		case key.Matches(msg, m.keymap.save):
+			if m.activeTab == TabEnvironment {
+				err := m.saveEnvironment()
+				if errors.Is(err, errVaultLocked) {
+					cmds = append(cmds, m.openVaultPrompt())
+					break
+				}
+				if err != nil {
+					return m, func() tea.Msg {
+						return errMsg{err: err}
+					}
+				}
+				m.err = nil
+				break
+			}
+
+			if m.activeTab == TabSettings {
+				if err := m.saveSettings(); err != nil {
+					return m, func() tea.Msg {
+						return errMsg{err: err}
+					}
 				}
+				m.err = nil
+				break
 			}
 
-			if m.collectionMap["headers"] == nil {
-				m.collectionMap["headers"] = map[string]string{}
+			if m.activeTab == TabAuth {
+				if err := m.saveAuth(); err != nil {
+					return m, func() tea.Msg {
+						return errMsg{err: err}
+					}
+				}
+				m.err = nil
+				break
 			}
 
-			maps.Copy(m.collectionMap["headers"].(map[string]string), headers)
+			if m.activeTab == TabScripts {
+				if err := m.saveScripts(); err != nil {
+					return m, func() tea.Msg {
+						return errMsg{err: err}
+					}
+				}
+				m.err = nil
+				break
+			}
 
-			if m.collectionMap[method] == nil {
-				m.collectionMap[method] = map[string]any{}
+			if m.activeTab == TabCookies {
+				if err := m.saveCookies(); err != nil {
+					return m, func() tea.Msg {
+						return errMsg{err: err}
+					}
+				}
+				m.err = nil
+				break
 			}
 
-			if m.collectionMap[method].(map[string]any)[path] == nil {
-				// TODO: add query params
-				m.collectionMap[method].(map[string]any)[path] = map[string]any{}
+			if strings.TrimSpace(m.collection.Value()) == "" {
+				break
 			}
 
-			collectionJson, err := json.MarshalIndent(m.collectionMap, "", "  ")
+			collection, err := parseCollection([]byte(m.collection.Value()))
 			if err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: fmt.Errorf("invalid collection: %w", err)}
+				}
+			}
+			m.setActiveCollection(collection)
+
+			if err := m.persistCollection(); err != nil {
 				return m, func() tea.Msg {
 					return errMsg{err: err}
 				}
 			}
+			m.err = nil
+		case key.Matches(msg, m.keymap.nextCollection):
+			if len(m.collectionFiles) == 0 {
+				break
+			}
+
+			next := 0
+			for i, file := range m.collectionFiles {
+				if file == m.collectionFile {
+					next = (i + 1) % len(m.collectionFiles)
+				}
+			}
 
-			m.collection.SetValue(string(collectionJson))
+			if err := m.loadCollection(m.collectionFiles[next]); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
 
+		case key.Matches(msg, m.keymap.switchEnvironment):
+			names, err := listEnvironmentNames()
+			if err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			cmds = append(cmds, m.openPrompt(PromptEnvironment, "Environment: ", "name to switch to or create, empty for none"))
+			m.prompt.SetSuggestions(names)
 		case key.Matches(msg, m.keymap.extractCollection):
-			collectionSplit := strings.Split(m.collection.Value(), "\n")
-			currentLine := collectionSplit[m.collection.Line()]
-			try, err := regexp.Compile(`(\s+)"(.*)": `)
+			if err := m.extractRequest(); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
+
+		case key.Matches(msg, m.keymap.importCollection):
+			cmds = append(cmds, m.openPrompt(PromptImport, "Import file: ", "path to a Postman v2.1 collection or OpenAPI 3 / Swagger 2 document"))
+		case key.Matches(msg, m.keymap.exportCollection):
+			if m.activeCollection == nil {
+				break
+			}
+			cmds = append(cmds, m.openPrompt(PromptExport, "Export to: ", "[postman|insomnia] path"))
+		case key.Matches(msg, m.keymap.pasteCurl):
+			cb, err := clipboard.ReadAll()
+			if err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+
+			c, err := parseCurl(cb)
 			if err != nil {
 				return m, func() tea.Msg {
 					return errMsg{err: err}
 				}
 			}
-			matches := try.FindStringSubmatch(currentLine)
 
-			// TODO: find out how to get the endpoint API call structure
+			m.inputs[0].SetValue(m.maskSecrets(c.url))
+			m.inputs[1].SetValue(c.method)
+			m.requestHeaders.SetValue(m.maskSecrets(formatHeaders(c.headers)))
+			m.requestBody.SetValue(m.maskSecrets(c.body))
+			m.clearRequestState()
+			m.setRequestSettings(c.settings)
+			m.err = nil
+			m.statusMessage = fmt.Sprintf("Pasted curl %s %s", c.method, c.url)
+			m.statusMessage += m.setWarnings("Pasted curl "+c.method+" "+c.url, c.warnings)
+		case key.Matches(msg, m.keymap.copyCurl):
+			req, err := m.buildRequest()
+			if err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
 
-			if len(matches) > 2 {
-				m.testExtract = fmt.Sprintf("'%s' - '%s'", matches[1], matches[2])
+			curl, err := generateSnippet(snippetIndex("curl"), req)
+			if err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
 			}
 
+			if err := clipboard.WriteAll(curl); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
+			m.statusMessage = "Copied request as curl"
+		case key.Matches(msg, m.keymap.nextLanguage):
+			if m.activeTab != TabCode {
+				break
+			}
+			m.snippetIndex = (m.snippetIndex + 1) % len(snippetGenerators)
+			m.updateSnippet()
+			m.responseView.SetContent(m.tabContent[TabCode])
+		case key.Matches(msg, m.keymap.toggleRaw):
+			if m.activeTab != TabResponseBody {
+				break
+			}
+			m.rawResponse = !m.rawResponse
+			cmds = append(cmds, m.renderResponseBody())
+			m.responseView.SetContent(m.tabContent[TabResponseBody])
+			m.statusMessage = "Showing the formatted response body"
+			if m.rawResponse {
+				m.statusMessage = "Showing the raw response body"
+			}
+		case key.Matches(msg, m.keymap.toggleTree):
+			if m.activeTab != TabResponseBody {
+				break
+			}
+			m.treeView = !m.treeView
+			cmds = append(cmds, m.renderResponseBody())
+			m.responseView.SetContent(m.tabContent[TabResponseBody])
+			m.responseView.GotoTop()
+			switch {
+			case !m.treeView:
+				m.statusMessage = "Showing the response body as text"
+			case m.jsonTree == nil:
+				m.statusMessage = "The response body is not json, showing it as text"
+			default:
+				m.statusMessage = "Showing the response body as a json tree"
+			}
+		case key.Matches(msg, m.keymap.copyPath):
+			if !m.isTreeActive() {
+				break
+			}
+			path := m.jsonTree.selectedNode().path()
+			if err := clipboard.WriteAll(path); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
+			m.statusMessage = "Copied " + path
+		case key.Matches(msg, m.keymap.copy) && m.isTreeActive():
+			node := m.jsonTree.selectedNode()
+			if err := clipboard.WriteAll(node.json()); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
+			m.statusMessage = "Copied the value of " + node.path()
+		case key.Matches(msg, m.keymap.copy):
+			if !m.isViewportTab() {
+				break
+			}
+			if err := clipboard.WriteAll(ansi.Strip(m.tabContent[m.activeTab])); err != nil {
+				return m, func() tea.Msg {
+					return errMsg{err: err}
+				}
+			}
+			m.err = nil
+			m.statusMessage = fmt.Sprintf("Copied %s to clipboard", m.tabs[m.activeTab])
 		case key.Matches(msg, m.keymap.nextView):
 			m.changeFocus()
 		case key.Matches(msg, m.keymap.prevView):
@@ -430,19 +804,30 @@ func (m model) View() string {
 	m.updateFocusView()
 	m.updateCursorPos(m.cursorPos)
 
-	m.requestHeaders.SetWidth(m.responseViewWidth)
-	m.requestHeaders.SetHeight(m.responseViewHeight)
-
-	m.collection.SetWidth(m.responseViewWidth)
-	m.collection.SetHeight(m.responseViewHeight)
+	for _, editor := range m.editors() {
+		editor.SetWidth(m.responseViewWidth)
+		editor.SetHeight(m.responseViewHeight)
+	}
 
-	m.requestBody.SetWidth(m.responseViewWidth)
-	m.requestBody.SetHeight(m.responseViewHeight)
+	// Show as many tabs as fit around the active one and share the window
+	// width (including its borders) between them
+	first, last := visibleTabs(m.tabs, int(m.activeTab), m.responseViewWidth+2)
+	labels := slices.Clone(m.tabs[first : last+1])
+	if first > 0 {
+		labels[0] = "‹ " + labels[0]
+	}
+	if last < len(m.tabs)-1 {
+		labels[len(labels)-1] += " ›"
+	}
 
-	tabWidth := m.responseViewWidth / len(m.tabs)
-	for i, t := range m.tabs {
+	tabsInnerWidth := max(m.responseViewWidth+2-2*len(labels), len(labels))
+	for _, label := range labels {
+		tabsInnerWidth -= lipgloss.Width(label)
+	}
+	extraWidth := max(tabsInnerWidth, 0) / len(labels)
+	for i, t := range labels {
 		var style lipgloss.Style
-		isFirst, isLast, isActive := i == 0, i == len(m.tabs)-1, i == int(m.activeTab)
+		isFirst, isLast, isActive := i == 0, i == len(labels)-1, first+i == int(m.activeTab)
 		if isActive {
 			style = activeTabStyle
 		} else {
@@ -458,17 +843,19 @@ func (m model) View() string {
 		} else if isLast && !isActive {
 			border.BottomRight = "┤"
 		}
-		if i == len(m.tabs)-1 {
-			tabWidth = (m.responseViewWidth / len(m.tabs)) + m.responseViewWidth%len(m.tabs) - 2*len(m.tabs)
+		tabWidth := lipgloss.Width(t) + extraWidth
+		if isLast {
+			tabWidth += max(tabsInnerWidth, 0) % len(labels)
 		}
+		tabWidth = min(tabWidth, max(m.responseViewWidth, 1))
 		style = style.Width(tabWidth).Border(border)
-		renderedTabs = append(renderedTabs, style.Render(t))
+		renderedTabs = append(renderedTabs, style.Render(truncate(t, tabWidth)))
 	}
 
 	row := lipgloss.JoinHorizontal(lipgloss.Top, renderedTabs...)
 
 	for i := range m.inputs {
-		b.WriteString(m.inputs[i].View())
+		b.WriteString(highlightUnresolved(m.inputs[i].View(), m.isResolved))
 		if m.startSpinner && i == 0 {
 			b.WriteString("    " + m.spinner.View())
 		}
@@ -480,23 +867,43 @@ func (m model) View() string {
 		}
 	}
 
-	if m.statusCode > 0 {
+	if m.statusCode > 0 || m.cancelled {
 		b.WriteString(m.statusCodeView.View())
 	}
 
+	matches, searchErr := m.searchMatches()
+	current := m.currentMatch(len(matches))
+	if len(matches) > 0 {
+		m.responseView.SetContent(m.highlightedContent(matches, current))
+	}
+
 	b.WriteRune('\n')
-	b.WriteString(m.testExtract)
+	statusLineStyle := lipgloss.NewStyle().MaxWidth(max(m.responseViewWidth, 1))
+	switch {
+	case m.promptAction == PromptSearch && m.search != "":
+		b.WriteString(statusLineStyle.Render(m.prompt.View() + "  " + searchStatus(matches, current, searchErr)))
+	case m.promptAction != PromptNone:
+		b.WriteString(statusLineStyle.Render(m.prompt.View()))
+	case m.err != nil:
+		b.WriteString(statusLineStyle.Inherit(errorStyle).Render(m.maskSecrets(m.err.Error())))
+	case m.search != "" && m.isSearchTab():
+		status := "/" + m.search + "  " + searchStatus(matches, current, searchErr)
+		if m.statusMessage != "" {
+			status += "  " + m.statusMessage
+		}
+		b.WriteString(statusLineStyle.Render(status))
+	default:
+		b.WriteString(statusLineStyle.Render(m.statusMessage))
+	}
 	b.WriteRune('\n')
 
 	b.WriteString(row)
 	b.WriteRune('\n')
-	switch m.activeTab {
-	case TabCollection:
-		b.WriteString(m.collection.View())
-	case TabRequestHeaders:
-		b.WriteString(m.requestHeaders.View())
-	case TabRequestBody:
-		b.WriteString(m.requestBody.View())
+	switch editor := m.editor(m.activeTab); {
+	case m.activeTab == TabRequestHeaders || m.activeTab == TabRequestBody:
+		b.WriteString(highlightUnresolved(editor.View(), m.isResolved))
+	case editor != nil:
+		b.WriteString(editor.View())
 	default:
 		b.WriteString(m.responseView.View())
 	}
@@ -508,8 +915,27 @@ func (m model) View() string {
 		m.keymap.nextTab,
 		m.keymap.prevTab,
 		m.keymap.run,
+		m.keymap.cancel,
 		m.keymap.addCollection,
 		m.keymap.extractCollection,
+		m.keymap.toggleRaw,
+		m.keymap.toggleTree,
+		m.keymap.toggleNode,
+		m.keymap.copyPath,
+		m.keymap.search,
+		m.keymap.nextMatch,
+		m.keymap.openHistory,
+		m.keymap.rerun,
+		m.keymap.filter,
+		m.keymap.save,
+		m.keymap.nextCollection,
+		m.keymap.switchEnvironment,
+		m.keymap.importCollection,
+		m.keymap.exportCollection,
+		m.keymap.pasteCurl,
+		m.keymap.copyCurl,
+		m.keymap.nextLanguage,
+		m.keymap.copy,
 		m.keymap.quit,
 	})
 
@@ -519,18 +945,21 @@ func (m model) View() string {
 }
 
 func (m *model) updateInputs(msg tea.Msg) tea.Cmd {
-	cmds := make([]tea.Cmd, len(m.inputs)+3)
+	var cmds []tea.Cmd
 
 	// Only text inputs with Focus() set will respond, so it's safe to simply
 	// update all of them here without any further logic.
-	var i int
-	for i = range m.inputs {
-		m.inputs[i], cmds[i] = m.inputs[i].Update(msg)
+	for i := range m.inputs {
+		var cmd tea.Cmd
+		m.inputs[i], cmd = m.inputs[i].Update(msg)
+		cmds = append(cmds, cmd)
 	}
 
-	m.requestHeaders, cmds[i] = m.requestHeaders.Update(msg)
-	m.requestBody, cmds[i+1] = m.requestBody.Update(msg)
-	m.collection, cmds[i+2] = m.collection.Update(msg)
+	for _, editor := range m.editors() {
+		var cmd tea.Cmd
+		*editor, cmd = editor.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 
 	return tea.Batch(cmds...)
 }
@@ -541,20 +970,15 @@ func (m *model) updateCursorPos(newPos int) {
 	case FocusInput:
 		m.cursorPos = min(newPos, len(m.inputs[m.focusInputIndex].Value()))
 	case FocusResponseView:
-		switch m.activeTab {
-		case TabCollection:
-			m.cursorPos = min(newPos, m.collection.LineInfo().CharWidth-1)
-		case TabRequestHeaders:
-			m.cursorPos = min(newPos, m.requestHeaders.LineInfo().CharWidth-1)
-		case TabRequestBody:
-			m.cursorPos = min(newPos, m.requestBody.LineInfo().CharWidth-1)
+		if editor := m.editor(m.activeTab); editor != nil {
+			m.cursorPos = min(newPos, editor.LineInfo().CharWidth-1)
 		}
 	}
 
 	m.inputs[m.focusInputIndex].SetCursor(m.cursorPos)
-	m.requestHeaders.SetCursor(m.cursorPos)
-	m.collection.SetCursor(m.cursorPos)
-	m.requestBody.SetCursor(m.cursorPos)
+	for _, editor := range m.editors() {
+		editor.SetCursor(m.cursorPos)
+	}
 }
 
 func (m *model) changeFocus() {
@@ -564,204 +988,1573 @@ func (m *model) changeFocus() {
 		for i := range m.inputs {
 			m.inputs[i].Blur()
 		}
-		switch m.activeTab {
-		case TabCollection:
-			m.collection.Focus()
-			m.cursorPos = m.collection.LineInfo().CharWidth - 2
-		case TabResponseHeaders:
-			m.requestHeaders.Focus()
-			m.cursorPos = m.requestHeaders.LineInfo().CharWidth - 2
-		case TabRequestBody:
-			m.requestBody.Focus()
-			m.cursorPos = m.requestBody.LineInfo().CharWidth - 2
+		if editor := m.editor(m.activeTab); editor != nil {
+			editor.Focus()
+			m.cursorPos = editor.LineInfo().CharWidth - 2
 		}
 	case FocusResponseView:
 		m.currentFocus = FocusInput
 		m.cursorPos = len(m.inputs[m.focusInputIndex].Value())
 		m.inputs[m.focusInputIndex].Focus()
-		m.collection.Blur()
-		m.requestHeaders.Blur()
-		m.requestBody.Blur()
+		m.blurEditors()
 	}
 }
 
-func (m *model) parseHeaders() map[string]string {
-	headers := map[string]string{}
-	for line := range strings.SplitSeq(m.requestHeaders.Value(), "\n") {
-		lineSplit := strings.Split(line, ":")
-		if len(lineSplit) > 1 {
-			key := lineSplit[0]
-			value := strings.TrimSpace(lineSplit[1])
-			if strings.Contains(value, "{{") && strings.Contains(value, "}}") {
-				start := strings.Index(value, "{{")
-				end := strings.Index(value, "}}")
-				if start != -1 && end != -1 && end > start {
-					envVar := value[start+2 : end]
-					envValue := os.Getenv(envVar)
-					if envValue != "" {
-						value = value[:start] + envValue + value[end+2:]
-					}
-				}
-			}
-			headers[key] = value
-		}
+// run sends the request in the editors, after its pre-request script has
+// run, and records it in the history once the response arrives.
+func (m *model) run() tea.Cmd {
+	draft := m.draftRequest()
+	m.preRequestResult = nil
+	if m.requestScripts == nil || m.requestScripts.PreRequest == "" {
+		return m.send(draft)
 	}
 
-	return headers
+	// A new run replaces the request still in flight
+	if m.startSpinner {
+		m.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.requestID++
+	m.cancelRequest = cancel
+	m.startSpinner = true
+	m.cancelled = false
+	m.responseTime = 0
+
+	return tea.Batch(m.spinner.Tick, runPreRequest(ctx, m.requestID, m.requestScripts.PreRequest, draft, m.variableLookup()))
 }
 
-func (m *model) updateFocusView() {
-	switch m.currentFocus {
-	case FocusResponseView:
-		for i := range m.inputs {
-			m.inputs[i].PromptStyle = noStyle
-			m.inputs[i].TextStyle = noStyle
+// send resolves the variables of draft and sends it.
+func (m *model) send(draft requestDraft) tea.Cmd {
+	spec, unresolved, secrets := m.resolveRequest(draft)
+	if len(unresolved) > 0 {
+		m.err = unresolvedVariablesError(unresolved)
+		return nil
+	}
+
+	entry := newHistoryEntry(spec, time.Now())
+
+	if len(secrets) > 0 {
+		if m.vault == nil {
+			return m.openVaultPrompt()
+		}
+
+		spec.secrets = m.vault.values(m.activeEnvironment.Name, secrets)
+		if missing := slices.DeleteFunc(secrets, func(name string) bool {
+			_, ok := spec.secrets[name]
+			return ok
+		}); len(missing) > 0 {
+			m.err = unresolvedVariablesError(missing)
+			return nil
 		}
-		windowStyle = windowStyle.BorderForeground(highlightColor)
-		inactiveTabStyle = inactiveTabStyle.BorderForeground(highlightColor)
-		activeTabStyle = inactiveTabStyle.Border(activeTabBorder, true)
-		m.responseView.Style = windowStyle
-	case FocusInput:
-		m.inputs[m.focusInputIndex].PromptStyle = focusedStyle
-		m.inputs[m.focusInputIndex].TextStyle = focusedStyle
-		windowStyle = windowStyle.BorderForeground(nonHighlightColor)
-		inactiveTabStyle = inactiveTabStyle.BorderForeground(nonHighlightColor)
-		activeTabStyle = inactiveTabStyle.Border(activeTabBorder, true)
-		m.responseView.Style = windowStyle
 	}
-}
 
-func initialModel() model {
-	m := model{
-		help:         help.New(),
-		inputs:       make([]textinput.Model, 2),
-		tabs:         []string{"Collection", "Request Headers", "Request Body", "Response Body", "Response Headers"},
-		currentFocus: FocusInput,
-		spinner:      spinner.New(),
-		keymap: keymap{
-			nextView: key.NewBinding(
-				key.WithKeys("tab"),
-				key.WithHelp("tab", "next view"),
-			),
-			prevView: key.NewBinding(
-				key.WithKeys("shift+tab"),
-				key.WithHelp("shift+tab", "prev view"),
-			),
-			nextTab: key.NewBinding(
-				key.WithKeys("alt+]"),
-				key.WithHelp("alt+]", "next tab"),
-			),
-			prevTab: key.NewBinding(
-				key.WithKeys("alt+["),
-				key.WithHelp("alt+[", "prev tab"),
-			),
-			left: key.NewBinding(
-				key.WithKeys("left"),
-				key.WithHelp("left", "move cursor left"),
-			),
-			right: key.NewBinding(
-				key.WithKeys("right"),
-				key.WithHelp("right", "move cursor right"),
-			),
-			up: key.NewBinding(
-				key.WithKeys("up"),
-				key.WithHelp("up", "move cursor up"),
-			),
-			down: key.NewBinding(
-				key.WithKeys("down"),
-				key.WithHelp("down", "move cursor down"),
-			),
-			h: key.NewBinding(
-				key.WithKeys("h"),
-				key.WithHelp("h", "scroll left"),
-			),
-			j: key.NewBinding(
-				key.WithKeys("j"),
-				key.WithHelp("j", "scroll down"),
-			),
-			k: key.NewBinding(
-				key.WithKeys("k"),
-				key.WithHelp("k", "scroll up"),
-			),
-			l: key.NewBinding(
-				key.WithKeys("l"),
-				key.WithHelp("l", "scroll right"),
-			),
-			paste: key.NewBinding(
-				key.WithKeys("ctrl+v"),
-				key.WithHelp("ctrl+v", "paste"),
-			),
-			run: key.NewBinding(
-				key.WithKeys("ctrl+r"),
-				key.WithHelp("ctrl+r", "run"),
-			),
-			addCollection: key.NewBinding(
-				key.WithKeys("alt+a"),
-				key.WithHelp("alt+a", "add to collection"),
-			),
-			extractCollection: key.NewBinding(
-				key.WithKeys("alt+e"),
-				key.WithHelp("alt+e", "extract from collection"),
-			),
-			quit: key.NewBinding(
-				key.WithKeys("ctrl+c"),
-				key.WithHelp("ctrl+c", "quit"),
-			),
-		},
+	// A new run replaces the request still in flight
+	if m.startSpinner {
+		m.cancel()
 	}
 
-	m.tabContent = make([]string, len(m.tabs))
+	ctx, cancel := context.WithCancel(context.Background())
+	m.requestID++
+	m.cancelRequest = cancel
+	m.inflight = &entry
+	m.startSpinner = true
+	m.cancelled = false
+	m.responseTime = 0
+
+	return tea.Batch(m.spinner.Tick, doRequest(ctx, m.requestID, spec, m.jar, m.tokens, m.transports))
+}
+
+// cancel aborts the request in flight. Its response is ignored once it
+// arrives since the request id no longer matches.
+func (m *model) cancel() {
+	if m.inflight != nil {
+		m.inflight.Error = "cancelled"
+		if err := m.recordHistory(); err != nil {
+			m.err = err
+		}
+	}
+	m.finishRequest()
+	m.requestID++
+
+	m.statusCode = 0
+	m.cancelled = true
+	m.statusCodeView.Style = statusCodeViewStyle.Background(nonHighlightColor)
+	m.statusCodeView.SetContent(centered("cancelled", m.statusCodeView.Width))
+}
+
+func (m *model) finishRequest() {
+	if m.cancelRequest != nil {
+		m.cancelRequest()
+		m.cancelRequest = nil
+	}
+	m.startSpinner = false
+}
+
+// showResponse shows msg in the response tabs, the returned command filters
+// its body.
+func (m *model) showResponse(msg responseMsg) tea.Cmd {
+	m.currentFocus = FocusResponseView
+	m.statusCode = msg.statusCode
+	m.activeTab = TabResponseBody
+	m.responseBody = msg.responseBody
+	m.responseHeaders = msg.responseHeaders
+	m.responseTime = msg.responseTime
+	m.jsonTree = nil
+	cmds := []tea.Cmd{m.filterResponseBody()}
+	if len(m.tabContent) > 0 {
+		cmds = append(cmds, m.renderResponseBody())
+		m.tabContent[TabResponseHeaders] = m.responseHeaders
+		m.tabContent[TabTimeline] = msg.timeline
+		m.responseView.SetContent(m.tabContent[m.activeTab])
+	}
+
+	if m.statusCode > 0 {
+		if m.statusCode < 300 {
+			statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#21FF4E"})
+		}
+
+		if m.statusCode > 299 && m.statusCode < 400 {
+			statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#FFC66D"})
+		}
+
+		if m.statusCode > 399 {
+			statusCodeViewStyle = statusCodeViewStyle.Background(lipgloss.CompleteColor{TrueColor: "#DA4939"})
+		}
+		statusMsg := fmt.Sprintf("%d %s", m.statusCode, http.StatusText(m.statusCode))
+		m.statusCodeView.SetContent(centered(statusMsg, m.statusCodeView.Width))
+		m.statusCodeView.Style = statusCodeViewStyle
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// renderResponseBody shows the response body pretty-printed and highlighted
+// when its format is known, or as received in raw mode or when it cannot be
+// parsed. The returned command formats a body that wasn't formatted before,
+// it is shown as received until then.
+func (m *model) renderResponseBody() tea.Cmd {
+	m.tabs[TabResponseBody] = "Response Body"
+	body, contentType := m.responseBody, headerLine(m.responseHeaders, "Content-Type")
+	if m.bodyFilter != "" {
+		m.tabs[TabResponseBody] = "Response Body: filtered"
+		switch {
+		case m.filtered == nil:
+			m.tabContent[TabResponseBody] = "Filtering…"
+			return nil
+		case m.filtered.err != nil:
+			m.tabContent[TabResponseBody] = errorStyle.Render(m.filtered.err.Error())
+			return nil
+		}
+		body, contentType = m.filtered.body, "application/json"
+	}
+
+	if m.treeView {
+		if m.jsonTree == nil {
+			m.jsonTree, _ = parseJSONTree(body)
+		}
+		if m.jsonTree != nil {
+			m.tabs[TabResponseBody] = "Response Body: tree"
+			if m.bodyFilter != "" {
+				m.tabs[TabResponseBody] = "Response Body: filtered tree"
+			}
+			m.tabContent[TabResponseBody] = m.jsonTree.String()
+			return nil
+		}
+	}
+
+	m.tabContent[TabResponseBody] = body
+	if m.rawResponse {
+		return nil
+	}
+
+	format := bodyFormat(contentType, body)
+	if format == "" {
+		return nil
+	}
+
+	if m.highlighted != nil && m.highlighted.source == body && m.highlighted.format == format {
+		m.tabContent[TabResponseBody] = m.highlighted.body
+		return nil
+	}
+	m.highlightID++
+	m.highlighted = nil
+
+	return highlightResponse(m.highlightID, body, format)
+}
+
+// setBodyFilter shows the response body filtered by expr, or unfiltered when
+// expr is empty.
+func (m *model) setBodyFilter(expr string) tea.Cmd {
+	expr = strings.TrimSpace(expr)
+	if expr == m.bodyFilter {
+		return nil
+	}
+
+	m.bodyFilter = expr
+	m.jsonTree = nil
+	cmd := m.filterResponseBody()
+	renderCmd := m.renderResponseBody()
+	if m.activeTab == TabResponseBody {
+		m.responseView.SetContent(m.tabContent[TabResponseBody])
+		m.responseView.GotoTop()
+	}
+
+	return tea.Batch(cmd, renderCmd)
+}
+
+// filterResponseBody starts filtering the response body by bodyFilter, in
+// place of the filter still running.
+func (m *model) filterResponseBody() tea.Cmd {
+	if m.cancelFilter != nil {
+		m.cancelFilter()
+		m.cancelFilter = nil
+	}
+	m.filterID++
+	m.filtered = nil
+	if m.bodyFilter == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), filterTimeout)
+	m.cancelFilter = cancel
+
+	return applyFilter(ctx, m.filterID, m.bodyFilter, m.responseBody)
+}
+
+// isTreeActive reports whether the response body is shown as a json tree
+// that has the focus.
+func (m *model) isTreeActive() bool {
+	return m.currentFocus == FocusResponseView && m.activeTab == TabResponseBody && m.treeView && m.jsonTree != nil
+}
+
+func (m *model) renderTree() {
+	m.tabContent[TabResponseBody] = m.jsonTree.String()
+	m.responseView.SetContent(m.tabContent[TabResponseBody])
+	m.keepInView(m.jsonTree.selected)
+}
+
+func (m *model) addToCollection(r *Request) error {
+	if m.requestSettings != nil {
+		settings := *m.requestSettings
+		r.Settings = &settings
+	}
+	if m.requestAuth != nil {
+		auth := *m.requestAuth
+		r.Auth = &auth
+	}
+	r.Tests = slices.Clone(m.requestTests)
+	r.Captures = slices.Clone(m.requestCaptures)
+	if m.requestScripts != nil {
+		scripts := *m.requestScripts
+		r.Scripts = &scripts
+	}
+
+	if m.activeCollection == nil {
+		m.activeCollection = &Collection{}
+	}
+
+	if existing := m.activeCollection.findRequest(r.Method, r.URL); existing != nil {
+		existing.update(r)
+	} else {
+		m.activeCollection.Requests = append(m.activeCollection.Requests, r)
+	}
+	m.requestPath = m.activeCollection.requestPath(r.Method, r.URL)
+	m.renderAuth()
+
+	if err := m.renderCollection(); err != nil {
+		return err
+	}
+
+	if err := m.persistCollection(); err != nil {
+		return err
+	}
+	m.statusMessage = fmt.Sprintf("Added %s %s to %q", r.Method, r.URL, m.activeCollection.Name)
+
+	return nil
+}
+
+// editor returns the textarea shown in tab, or nil for the tabs rendered in
+// the read-only response viewport.
+func (m *model) editor(tab Tab) *textarea.Model {
+	switch tab {
+	case TabCollection:
+		return &m.collection
+	case TabEnvironment:
+		return &m.environment
+	case TabRequestHeaders:
+		return &m.requestHeaders
+	case TabRequestBody:
+		return &m.requestBody
+	case TabAuth:
+		return &m.auth
+	case TabScripts:
+		return &m.scripts
+	case TabSettings:
+		return &m.clientSettings
+	case TabCookies:
+		return &m.cookies
+	}
+
+	return nil
+}
+
+func (m *model) editors() []*textarea.Model {
+	return []*textarea.Model{&m.collection, &m.environment, &m.requestHeaders, &m.requestBody, &m.auth, &m.scripts, &m.clientSettings, &m.cookies}
+}
+
+func (m *model) blurEditors() {
+	for _, editor := range m.editors() {
+		editor.Blur()
+	}
+}
+
+// buildRequest creates the http request for the current editor state, the same
+// way it is sent on run. Unresolved and secret variables are left in place.
+func (m *model) buildRequest() (*http.Request, error) {
+	spec, _, _ := m.resolveRequest(m.draftRequest())
+	return buildRequest(spec)
+}
+
+func (m *model) updateSnippet() {
+	req, err := m.buildRequest()
+	if err != nil {
+		m.tabContent[TabCode] = err.Error()
+		return
+	}
+
+	snippet, err := generateSnippet(m.snippetIndex, req)
+	if err != nil {
+		m.tabContent[TabCode] = err.Error()
+		return
+	}
+
+	m.tabs[TabCode] = "Code: " + snippetGenerators[m.snippetIndex].name
+	m.tabContent[TabCode] = snippet
+}
+
+// isViewportTab reports whether the active tab is rendered in the read-only
+// response viewport.
+const noWarnings = "Items skipped when importing a collection or pasting a curl command show up here."
+
+// setWarnings lists the warnings of an import or a pasted curl command in the
+// Warnings tab, and returns the note about them for the status line.
+func (m *model) setWarnings(source string, warnings []string) string {
+	m.tabs[TabWarnings] = "Warnings"
+	m.tabContent[TabWarnings] = noWarnings
+	note := ""
+	if len(warnings) > 0 {
+		var b strings.Builder
+		b.WriteString(source + "\n")
+		for _, warning := range warnings {
+			b.WriteString("\n· " + warning)
+		}
+		m.tabs[TabWarnings] = fmt.Sprintf("Warnings: %d", len(warnings))
+		m.tabContent[TabWarnings] = b.String()
+		note = fmt.Sprintf(", %d %s, see the Warnings tab", len(warnings), plural(len(warnings), "warning", "warnings"))
+	}
+
+	if m.activeTab == TabWarnings {
+		m.responseView.SetContent(m.tabContent[TabWarnings])
+		m.responseView.GotoTop()
+	}
+
+	return note
+}
+
+func (m *model) isViewportTab() bool {
+	return m.editor(m.activeTab) == nil
+}
+
+// resolveRequest substitutes the {{variables}} in the url, method, headers and
+// body, and returns the names of the variables that have no value and of the
+// secret variables that are left for doRequest to fill in.
+func (m *model) resolveRequest(draft requestDraft) (requestSpec, []string, []string) {
+	var unresolved, secrets []string
+	lookup := func(name string) (string, bool) {
+		if m.activeEnvironment.isSecret(name) {
+			secrets = append(secrets, name)
+			return "{{" + name + "}}", true
+		}
+
+		return m.lookupVariable(name)
+	}
+	resolve := func(s string) string {
+		s, missing := substituteVariables(s, lookup)
+		unresolved = append(unresolved, missing...)
+		return s
+	}
+
+	spec := requestSpec{
+		url:      resolve(draft.url),
+		method:   resolve(draft.method),
+		headers:  map[string]string{},
+		body:     resolve(draft.body),
+		settings: m.globalSettings.merge(m.requestSettings),
+	}
+	if auth := m.effectiveAuth(); auth != nil && auth.Type != AuthNone {
+		spec.auth = auth.substitute(resolve)
+	}
+	for key, value := range draft.headers {
+		spec.headers[resolve(key)] = resolve(value)
+	}
+
+	slices.Sort(unresolved)
+	slices.Sort(secrets)
+
+	return spec, slices.Compact(unresolved), slices.Compact(secrets)
+}
+
+// draftRequest returns the request in the editors.
+func (m *model) draftRequest() requestDraft {
+	return requestDraft{
+		url:     m.inputs[0].Value(),
+		method:  m.inputs[1].Value(),
+		headers: m.rawHeaders(),
+		body:    m.requestBody.Value(),
+	}
+}
+
+// lookupVariable resolves a variable from the active environment, then the
+// variables set by scripts, the active collection and finally the OS
+// environment.
+func (m *model) lookupVariable(name string) (string, bool) {
+	if m.activeEnvironment != nil {
+		if value, ok := m.activeEnvironment.Variables[name]; ok {
+			return value, true
+		}
+	}
+
+	if value, ok := m.variables[name]; ok {
+		return value, true
+	}
+
+	if m.activeCollection != nil {
+		if value, ok := m.activeCollection.Variables[name]; ok {
+			return value, true
+		}
+	}
+
+	return os.LookupEnv(name)
+}
+
+// variableLookup returns lookupVariable over a copy of the variables, for
+// scripts that run off the ui loop.
+func (m *model) variableLookup() func(string) (string, bool) {
+	variables := map[string]string{}
+	if m.activeCollection != nil {
+		maps.Copy(variables, m.activeCollection.Variables)
+	}
+	maps.Copy(variables, m.variables)
+	if m.activeEnvironment != nil {
+		maps.Copy(variables, m.activeEnvironment.Variables)
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := variables[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+}
+
+// isResolved reports whether a {{name}} placeholder is substituted when the
+// request is sent, secrets are resolved once the vault is unlocked.
+func (m *model) isResolved(name string) bool {
+	if m.activeEnvironment.isSecret(name) {
+		return true
+	}
+	_, ok := m.lookupVariable(name)
+
+	return ok
+}
+
+func unresolvedVariablesError(names []string) error {
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		placeholders[i] = "{{" + name + "}}"
+	}
+
+	return fmt.Errorf("request not sent, unresolved variables: %s", strings.Join(placeholders, ", "))
+}
+
+// headerLine returns the value of header name in headers formatted as
+// "Name: value" lines.
+func headerLine(headers, name string) string {
+	value, _ := lookupHeaderLine(headers, name)
+	return value
+}
+
+func lookupHeaderLine(headers, name string) (string, bool) {
+	for line := range strings.SplitSeq(headers, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found && strings.EqualFold(strings.TrimSpace(key), name) {
+			return strings.TrimSpace(value), true
+		}
+	}
+
+	return "", false
+}
+
+// rawHeaders returns the request headers as typed, with {{VAR}} placeholders
+// left untouched.
+func (m *model) rawHeaders() map[string]string {
+	headers := map[string]string{}
+	for line := range strings.SplitSeq(m.requestHeaders.Value(), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found && strings.TrimSpace(key) != "" {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return headers
+}
+
+// extractRequest loads the collection request under the cursor into the
+// url, method, headers and body editors.
+func (m *model) extractRequest() error {
+	value := m.collection.Value()
+	info := m.collection.LineInfo()
+	offset, err := cursorOffset(value, m.collection.Line(), info.StartColumn+info.ColumnOffset)
+	if err != nil {
+		return err
+	}
+
+	keyPath, err := jsonKeyPathAt([]byte(value), offset)
+	if err != nil {
+		return fmt.Errorf("invalid collection: %w", err)
+	}
+
+	collection, err := parseCollection([]byte(value))
+	if err != nil {
+		return fmt.Errorf("invalid collection: %w", err)
+	}
+
+	r := collection.requestAt(keyPath)
+	if r == nil {
+		return errors.New("cursor is not on a collection request")
+	}
+
+	m.inputs[0].SetValue(m.maskSecrets(r.FullURL()))
+	m.inputs[1].SetValue(r.Method)
+	m.requestHeaders.SetValue(m.maskSecrets(formatHeaders(r.effectiveHeaders())))
+	m.requestBody.SetValue(m.maskSecrets(r.Body))
+	m.setRequestSettings(r.Settings)
+	m.requestPath = keyPath
+	m.setRequestAuth(r.Auth)
+	m.setRequestTests(r.Tests)
+	m.requestCaptures = slices.Clone(r.Captures)
+	m.setRequestScripts(r.Scripts)
+	m.statusMessage = fmt.Sprintf("Loaded %s %s", r.Method, r.FullURL())
+
+	return nil
+}
+
+func isHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+func formatHeaders(headers map[string]string) string {
+	var b strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(headers)) {
+		fmt.Fprintf(&b, "%s: %s\n", key, headers[key])
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func stringMap(v any) map[string]string {
+	result := map[string]string{}
+	switch v := v.(type) {
+	case map[string]string:
+		maps.Copy(result, v)
+	case map[string]any:
+		for key, value := range v {
+			result[key] = fmt.Sprint(value)
+		}
+	}
+
+	return result
+}
+
+func (m *model) updateFocusView() {
+	switch m.currentFocus {
+	case FocusResponseView:
+		for i := range m.inputs {
+			m.inputs[i].PromptStyle = noStyle
+			m.inputs[i].TextStyle = noStyle
+		}
+		windowStyle = windowStyle.BorderForeground(highlightColor)
+		inactiveTabStyle = inactiveTabStyle.BorderForeground(highlightColor)
+		activeTabStyle = inactiveTabStyle.Border(activeTabBorder, true)
+		m.responseView.Style = windowStyle
+	case FocusInput:
+		m.inputs[m.focusInputIndex].PromptStyle = focusedStyle
+		m.inputs[m.focusInputIndex].TextStyle = focusedStyle
+		windowStyle = windowStyle.BorderForeground(nonHighlightColor)
+		inactiveTabStyle = inactiveTabStyle.BorderForeground(nonHighlightColor)
+		activeTabStyle = inactiveTabStyle.Border(activeTabBorder, true)
+		m.responseView.Style = windowStyle
+	}
+}
+
+func initialModel() model {
+	m := model{
+		help:         help.New(),
+		inputs:       make([]textinput.Model, 2),
+		tabs:         []string{"Collection", "History", "Environment", "Request Headers", "Request Body", "Auth", "Scripts", "Settings", "Cookies", "Response Body", "Response Headers", "Tests", "Timeline", "Code", "Warnings"},
+		currentFocus: FocusInput,
+		spinner:      spinner.New(),
+		searchCache:  &searchCache{},
+		keymap: keymap{
+			nextView: key.NewBinding(
+				key.WithKeys("tab"),
+				key.WithHelp("tab", "next view"),
+			),
+			prevView: key.NewBinding(
+				key.WithKeys("shift+tab"),
+				key.WithHelp("shift+tab", "prev view"),
+			),
+			nextTab: key.NewBinding(
+				key.WithKeys("alt+]"),
+				key.WithHelp("alt+]", "next tab"),
+			),
+			prevTab: key.NewBinding(
+				key.WithKeys("alt+["),
+				key.WithHelp("alt+[", "prev tab"),
+			),
+			left: key.NewBinding(
+				key.WithKeys("left"),
+				key.WithHelp("left", "move cursor left"),
+			),
+			right: key.NewBinding(
+				key.WithKeys("right"),
+				key.WithHelp("right", "move cursor right"),
+			),
+			up: key.NewBinding(
+				key.WithKeys("up"),
+				key.WithHelp("up", "move cursor up"),
+			),
+			down: key.NewBinding(
+				key.WithKeys("down"),
+				key.WithHelp("down", "move cursor down"),
+			),
+			h: key.NewBinding(
+				key.WithKeys("h"),
+				key.WithHelp("h", "scroll left"),
+			),
+			j: key.NewBinding(
+				key.WithKeys("j"),
+				key.WithHelp("j", "scroll down"),
+			),
+			k: key.NewBinding(
+				key.WithKeys("k"),
+				key.WithHelp("k", "scroll up"),
+			),
+			l: key.NewBinding(
+				key.WithKeys("l"),
+				key.WithHelp("l", "scroll right"),
+			),
+			paste: key.NewBinding(
+				key.WithKeys("ctrl+v"),
+				key.WithHelp("ctrl+v", "paste"),
+			),
+			run: key.NewBinding(
+				key.WithKeys("ctrl+r"),
+				key.WithHelp("ctrl+r", "run"),
+			),
+			cancel: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "cancel request"),
+			),
+			addCollection: key.NewBinding(
+				key.WithKeys("alt+a"),
+				key.WithHelp("alt+a", "add to collection"),
+			),
+			extractCollection: key.NewBinding(
+				key.WithKeys("alt+e"),
+				key.WithHelp("alt+e", "extract from collection"),
+			),
+			toggleRaw: key.NewBinding(
+				key.WithKeys("alt+p"),
+				key.WithHelp("alt+p", "toggle raw body"),
+			),
+			toggleTree: key.NewBinding(
+				key.WithKeys("alt+t"),
+				key.WithHelp("alt+t", "toggle json tree"),
+			),
+			toggleNode: key.NewBinding(
+				key.WithKeys(" "),
+				key.WithHelp("space", "expand/collapse"),
+			),
+			copyPath: key.NewBinding(
+				key.WithKeys("alt+y"),
+				key.WithHelp("alt+y", "copy json path"),
+			),
+			search: key.NewBinding(
+				key.WithKeys("/"),
+				key.WithHelp("/", "search"),
+			),
+			nextMatch: key.NewBinding(
+				key.WithKeys("n"),
+				key.WithHelp("n/N", "next/prev match"),
+			),
+			prevMatch: key.NewBinding(
+				key.WithKeys("N"),
+				key.WithHelp("N", "prev match"),
+			),
+			openHistory: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "open history entry"),
+			),
+			rerun: key.NewBinding(
+				key.WithKeys("alt+r"),
+				key.WithHelp("alt+r", "re-run history entry"),
+			),
+			filter: key.NewBinding(
+				key.WithKeys("ctrl+f"),
+				key.WithHelp("ctrl+f", "filter history or body"),
+			),
+			save: key.NewBinding(
+				key.WithKeys("ctrl+s"),
+				key.WithHelp("ctrl+s", "save"),
+			),
+			nextCollection: key.NewBinding(
+				key.WithKeys("alt+n"),
+				key.WithHelp("alt+n", "next collection"),
+			),
+			switchEnvironment: key.NewBinding(
+				key.WithKeys("ctrl+e"),
+				key.WithHelp("ctrl+e", "switch environment"),
+			),
+			importCollection: key.NewBinding(
+				key.WithKeys("alt+i"),
+				key.WithHelp("alt+i", "import collection"),
+			),
+			exportCollection: key.NewBinding(
+				key.WithKeys("alt+x"),
+				key.WithHelp("alt+x", "export collection"),
+			),
+			pasteCurl: key.NewBinding(
+				key.WithKeys("alt+v"),
+				key.WithHelp("alt+v", "paste as curl"),
+			),
+			copyCurl: key.NewBinding(
+				key.WithKeys("alt+c"),
+				key.WithHelp("alt+c", "copy as curl"),
+			),
+			nextLanguage: key.NewBinding(
+				key.WithKeys("alt+l"),
+				key.WithHelp("alt+l", "next language"),
+			),
+			copy: key.NewBinding(
+				key.WithKeys("ctrl+y"),
+				key.WithHelp("ctrl+y", "copy"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "quit"),
+			),
+		},
+	}
+
+	m.tabContent = make([]string, len(m.tabs))
+	m.tabContent[TabWarnings] = noWarnings
 
 	var t textinput.Model
 	for i := range m.inputs {
 		t = textinput.New()
 		t.Cursor.Style = cursorStyle
 
-		switch i {
-		// URL
-		case 0:
-			t.CharLimit = 256
-			t.SetValue(placeHolderUrl)
-			m.cursorPos = len(placeHolderUrl)
-			t.Width = t.CharLimit
-			t.Focus()
-			t.PromptStyle = focusedStyle
-			t.TextStyle = focusedStyle
-		// Headers
-		case 1:
-			t.CharLimit = 10
-			t.SetValue(placeHolderMethod)
-			t.Width = t.CharLimit
+		switch i {
+		// URL
+		case 0:
+			t.CharLimit = 256
+			t.SetValue(placeHolderUrl)
+			m.cursorPos = len(placeHolderUrl)
+			t.Width = t.CharLimit
+			t.Focus()
+			t.PromptStyle = focusedStyle
+			t.TextStyle = focusedStyle
+		// Headers
+		case 1:
+			t.CharLimit = 10
+			t.SetValue(placeHolderMethod)
+			t.Width = t.CharLimit
+		}
+
+		m.inputs[i] = t
+	}
+
+	m.spinner.Style = spinnerStyle
+	m.spinner.Spinner = spinner.Moon
+
+	m.responseView = viewport.New(78, 20)
+	m.responseView.Style = windowStyle
+
+	for _, editor := range m.editors() {
+		*editor = textarea.New()
+		editor.Cursor.Style = cursorStyle
+		editor.BlurredStyle.Base = windowStyle.BorderForeground(nonHighlightColor)
+		editor.FocusedStyle.Base = windowStyle.BorderForeground(highlightColor)
+	}
+	m.environment.Placeholder = "Press ctrl+e to select an environment, then add NAME=value lines"
+
+	m.statusCodeView = viewport.New(16, 1)
+	m.statusCodeView.Style = statusCodeViewStyle
+
+	m.prompt = textinput.New()
+	m.prompt.Cursor.Style = cursorStyle
+	m.prompt.PromptStyle = focusedStyle
+	m.prompt.ShowSuggestions = true
+
+	path, err := historyPath()
+	if err == nil {
+		m.history, err = loadHistory(path)
+	}
+	if err != nil {
+		m.err = err
+	}
+	m.renderHistory()
+
+	path, err = settingsPath()
+	if err == nil {
+		m.globalSettings, err = loadSettings(path)
+	}
+	if err != nil {
+		m.err = err
+	}
+	m.setRequestSettings(nil)
+
+	m.jar = &cookieJar{}
+	if m.globalSettings.persistCookies() {
+		path, err = cookiesPath()
+		if err == nil {
+			m.jar.cookies, err = loadCookies(path)
+		}
+		if err != nil {
+			m.err = err
+		}
+	}
+	m.cookies.SetValue(formatCookies(m.jar.all()))
+	m.cookies.Placeholder = "Cookies set by responses show up here, grouped by [domain]"
+
+	m.tokens = &tokenCache{}
+	m.transports = &transportCache{}
+	m.setRequestAuth(nil)
+	m.setRequestTests(nil)
+	m.setRequestScripts(nil)
+
+	files, err := listCollectionFiles()
+	if err != nil {
+		m.err = err
+		return m
+	}
+	m.collectionFiles = files
+
+	if len(m.collectionFiles) > 0 {
+		if err := m.loadCollection(m.collectionFiles[0]); err != nil {
+			m.err = err
+		}
+	}
+
+	return m
+}
+
+func (m *model) loadCollection(path string) error {
+	collection, err := loadCollection(path)
+	if err != nil {
+		return err
+	}
+
+	m.activeCollection = collection
+	m.collectionFile = path
+	m.requestPath = nil
+	m.renderAuth()
+
+	return m.renderCollection()
+}
+
+// setActiveCollection replaces the active collection by an edited one, in
+// which requestPath points to the same request, so it inherits the auth of
+// its new folders.
+func (m *model) setActiveCollection(collection *Collection) {
+	var current *Request
+	if m.activeCollection != nil {
+		current = m.activeCollection.requestAt(m.requestPath)
+	}
+	m.activeCollection = collection
+	m.requestPath = nil
+	if current != nil {
+		m.requestPath = collection.requestPath(current.Method, current.URL)
+	}
+	m.renderAuth()
+}
+
+func (m *model) renderCollection() error {
+	collectionJson, err := json.MarshalIndent(m.activeCollection, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	m.collection.SetValue(string(collectionJson))
+
+	return nil
+}
+
+func (m *model) persistCollection() error {
+	path, err := collectionPath(m.activeCollection.Name)
+	if err != nil {
+		return err
+	}
+	// Names like "My API" and "my-api" share a file name, a collection keeps
+	// the file it was given and never takes the file of another one
+	if m.collectionFile != "" && isAvailablePath(m.collectionFile, path) {
+		path = m.collectionFile
+	} else {
+		path = availablePath(path)
+	}
+
+	if err := saveCollection(path, m.activeCollection); err != nil {
+		return err
+	}
+
+	// Renaming a collection moves it to a new file
+	if m.collectionFile != "" && m.collectionFile != path {
+		if err := os.Remove(m.collectionFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
 		}
+		m.collectionFiles = slices.DeleteFunc(m.collectionFiles, func(file string) bool {
+			return file == m.collectionFile
+		})
+	}
 
-		m.inputs[i] = t
+	m.collectionFile = path
+	if !slices.Contains(m.collectionFiles, path) {
+		m.collectionFiles = append(m.collectionFiles, path)
+		slices.Sort(m.collectionFiles)
 	}
 
-	m.spinner.Style = spinnerStyle
-	m.spinner.Spinner = spinner.Moon
+	return nil
+}
 
-	m.responseView = viewport.New(78, 20)
-	m.responseView.Style = windowStyle
+// switchEnvironment activates the environment called name, creating it when it
+// does not exist yet. An empty name deactivates the current environment.
+func (m *model) switchEnvironment(name string) error {
+	if name == "" {
+		m.activeEnvironment = nil
+		m.environment.SetValue("")
+		m.tabs[TabEnvironment] = "Environment"
+		m.statusMessage = "No active environment"
+		return nil
+	}
+
+	path, err := environmentPath(name)
+	if err != nil {
+		return err
+	}
+
+	env, err := loadEnvironment(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		env = &Environment{Name: name}
+		if err := saveEnvironment(path, env); err != nil {
+			return err
+		}
+		m.statusMessage = fmt.Sprintf("Created environment %q", name)
+	case err != nil:
+		return err
+	default:
+		m.statusMessage = fmt.Sprintf("Switched to environment %q", env.Name)
+	}
 
-	m.collection = textarea.New()
-	m.collection.Cursor.Style = cursorStyle
-	m.collection.BlurredStyle.Base = windowStyle.BorderForeground(nonHighlightColor)
-	m.collection.FocusedStyle.Base = windowStyle.BorderForeground(highlightColor)
+	m.activeEnvironment = env
+	m.environment.SetValue(formatVariables(env))
+	m.tabs[TabEnvironment] = "Env: " + env.Name
 
-	m.requestHeaders = textarea.New()
-	m.requestHeaders.Cursor.Style = cursorStyle
-	m.requestHeaders.BlurredStyle.Base = windowStyle.BorderForeground(nonHighlightColor)
-	m.requestHeaders.FocusedStyle.Base = windowStyle.BorderForeground(highlightColor)
+	return nil
+}
 
-	m.requestBody = textarea.New()
-	m.requestBody.Cursor.Style = cursorStyle
-	m.requestBody.BlurredStyle.Base = windowStyle.BorderForeground(nonHighlightColor)
-	m.requestBody.FocusedStyle.Base = windowStyle.BorderForeground(highlightColor)
+func (m *model) saveEnvironment() error {
+	if m.activeEnvironment == nil {
+		return errors.New("no active environment, press ctrl+e to select one")
+	}
 
-	m.statusCodeView = viewport.New(16, 1)
-	m.statusCodeView.Style = statusCodeViewStyle
+	env := m.activeEnvironment
+	variables, secrets, err := parseVariables(m.environment.Value())
+	if err != nil {
+		return fmt.Errorf("invalid environment: %w", err)
+	}
 
-	return m
+	names := slices.Sorted(maps.Keys(secrets))
+	if m.vault == nil {
+		// Without the vault only the masked secrets can be saved unchanged
+		for _, value := range secrets {
+			if value != secretMask {
+				return errVaultLocked
+			}
+		}
+		if !slices.Equal(names, slices.Sorted(slices.Values(env.Secrets))) {
+			return errVaultLocked
+		}
+	} else {
+		for name, value := range secrets {
+			if value != secretMask {
+				continue
+			}
+			existing, ok := m.vault.get(env.Name, name)
+			if !ok {
+				return fmt.Errorf("invalid environment: secret %s has no value", name)
+			}
+			secrets[name] = existing
+		}
+
+		if m.vault.setAll(env.Name, secrets) {
+			if err := m.vault.save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	path, err := environmentPath(env.Name)
+	if err != nil {
+		return err
+	}
+
+	env.Variables = variables
+	env.Secrets = names
+	if err := saveEnvironment(path, env); err != nil {
+		return err
+	}
+	m.environment.SetValue(formatVariables(env))
+	m.statusMessage = fmt.Sprintf("Saved environment %q", m.activeEnvironment.Name)
+
+	return nil
+}
+
+func (m *model) saveSettings() error {
+	global, request, err := parseSettings(m.clientSettings.Value())
+	if err != nil {
+		return fmt.Errorf("invalid settings: %w", err)
+	}
+
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := saveSettings(path, global); err != nil {
+		return err
+	}
+
+	m.globalSettings = global
+	m.requestSettings = request
+	m.statusMessage = "Saved settings"
+
+	// Persisting starts with the current jar, turning it off forgets it
+	if !global.persistCookies() {
+		path, err := cookiesPath()
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	return m.storeCookies()
+}
+
+// storeCookies refreshes the cookies editor from the jar and saves the jar
+// when cookies are persisted.
+func (m *model) storeCookies() error {
+	cookies := m.jar.all()
+	m.cookies.SetValue(formatCookies(cookies))
+
+	if !m.globalSettings.persistCookies() {
+		return nil
+	}
+
+	path, err := cookiesPath()
+	if err != nil {
+		return err
+	}
+
+	return saveCookies(path, cookies)
+}
+
+func (m *model) saveCookies() error {
+	cookies, err := parseCookies(m.cookies.Value())
+	if err != nil {
+		return fmt.Errorf("invalid cookies: %w", err)
+	}
+	m.jar.replace(cookies)
+
+	if err := m.storeCookies(); err != nil {
+		return err
+	}
+	m.statusMessage = fmt.Sprintf("Saved %d cookies", len(cookies))
+
+	return nil
+}
+
+func (m *model) setRequestSettings(settings *Settings) {
+	m.requestSettings = nil
+	if settings != nil && !settings.isEmpty() {
+		clone := *settings
+		m.requestSettings = &clone
+	}
+	m.clientSettings.SetValue(formatSettings(m.globalSettings, m.requestSettings))
+}
+
+func (m *model) setRequestAuth(auth *Auth) {
+	m.requestAuth = nil
+	if auth != nil {
+		clone := *auth
+		m.requestAuth = &clone
+	}
+	m.renderAuth()
+}
+
+// clearRequestState forgets the collection request loaded in the editors,
+// with its settings, auth, tests, captures and scripts.
+func (m *model) clearRequestState() {
+	m.setRequestSettings(nil)
+	m.requestPath = nil
+	m.setRequestAuth(nil)
+	m.setRequestTests(nil)
+	m.requestCaptures = nil
+	m.setRequestScripts(nil)
+}
+
+func (m *model) setRequestTests(tests []string) {
+	m.requestTests = slices.Clone(tests)
+	m.tabs[TabTests] = "Tests"
+	m.tabContent[TabTests] = formatPendingTests(m.requestTests)
+}
+
+// captureVariables sets the variables captured from the response of the
+// current request.
+func (m *model) captureVariables(msg responseMsg) error {
+	if len(m.requestCaptures) == 0 {
+		return nil
+	}
+
+	variables, captureErr := runCaptures(m.requestCaptures, msg)
+	if err := m.setVariables(variables); err != nil {
+		return err
+	}
+	if len(variables) > 0 {
+		m.statusMessage = "Captured " + strings.Join(slices.Sorted(maps.Keys(variables)), ", ")
+	}
+
+	return captureErr
+}
+
+// runTests checks the response against the tests of the current request and
+// runs its post-response script.
+func (m *model) runTests(msg responseMsg, sent *HistoryEntry) tea.Cmd {
+	var results []testResult
+	var logs []string
+	if m.preRequestResult != nil {
+		results = append(results, m.preRequestResult.tests...)
+		logs = append(logs, m.preRequestResult.logs...)
+	}
+	results = append(results, runTests(m.requestTests, msg)...)
+
+	if m.requestScripts == nil || m.requestScripts.PostResponse == "" {
+		m.reportTests(results, logs)
+		return nil
+	}
+
+	m.tabContent[TabTests] = "Running the post-response script…"
+	return runPostResponse(m.requestID, m.requestScripts.PostResponse, sent, msg, m.variableLookup(), results, logs)
+}
+
+// reportTests shows the test results and script logs in the Tests tab.
+func (m *model) reportTests(results []testResult, logs []string) {
+	if len(results) == 0 && len(logs) == 0 {
+		m.tabContent[TabTests] = formatPendingTests(m.requestTests)
+		return
+	}
+
+	report, failed := formatTestResults(results, logs)
+	m.tabContent[TabTests] = report
+	m.tabs[TabTests] = "Tests"
+	if len(results) > 0 {
+		m.tabs[TabTests] = fmt.Sprintf("Tests: %d/%d passed", len(results)-failed, len(results))
+		if failed > 0 {
+			m.statusMessage = fmt.Sprintf("%d of %d tests failed", failed, len(results))
+		} else {
+			m.statusMessage = fmt.Sprintf("All %d tests passed", len(results))
+		}
+	}
+}
+
+func (m *model) setRequestScripts(scripts *Scripts) {
+	m.requestScripts = nil
+	if scripts != nil && !scripts.isEmpty() {
+		clone := *scripts
+		m.requestScripts = &clone
+	}
+	m.scripts.SetValue(formatScripts(m.requestScripts))
+}
+
+func (m *model) saveScripts() error {
+	scripts, err := parseScripts(m.scripts.Value())
+	if err != nil {
+		return fmt.Errorf("invalid scripts: %w", err)
+	}
+
+	m.setRequestScripts(scripts)
+	m.statusMessage = "Saved scripts for the current request"
+
+	return nil
+}
+
+// setVariables stores the variables set by a script in the active environment,
+// or for the session when there is none.
+func (m *model) setVariables(variables map[string]string) error {
+	if len(variables) == 0 {
+		return nil
+	}
+
+	env := m.activeEnvironment
+	if env == nil {
+		if m.variables == nil {
+			m.variables = map[string]string{}
+		}
+		maps.Copy(m.variables, variables)
+		return nil
+	}
+
+	changed := false
+	var secrets map[string]string
+	for name, value := range variables {
+		if env.isSecret(name) {
+			if secrets == nil {
+				secrets = map[string]string{}
+			}
+			secrets[name] = value
+			continue
+		}
+		if current, ok := env.Variables[name]; ok && current == value {
+			continue
+		}
+		if env.Variables == nil {
+			env.Variables = map[string]string{}
+		}
+		env.Variables[name] = value
+		changed = true
+	}
+
+	if len(secrets) > 0 {
+		if m.vault == nil {
+			return fmt.Errorf("secrets %s can't be set while the vault is locked", strings.Join(slices.Sorted(maps.Keys(secrets)), ", "))
+		}
+		all := maps.Clone(m.vault.secrets[env.Name])
+		if all == nil {
+			all = map[string]string{}
+		}
+		maps.Copy(all, secrets)
+		if m.vault.setAll(env.Name, all) {
+			if err := m.vault.save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	path, err := environmentPath(env.Name)
+	if err != nil {
+		return err
+	}
+	if err := saveEnvironment(path, env); err != nil {
+		return err
+	}
+	m.environment.SetValue(formatVariables(env))
+
+	return nil
+}
+
+// effectiveAuth returns the auth of the current request, or the auth it
+// inherits.
+func (m *model) effectiveAuth() *Auth {
+	if m.requestAuth != nil {
+		return m.requestAuth
+	}
+
+	auth, _ := m.activeCollection.authFor(m.requestPath)
+	return auth
+}
+
+func (m *model) renderAuth() {
+	inherited, from := m.activeCollection.authFor(m.requestPath)
+	m.auth.SetValue(m.maskSecrets(formatAuth(m.requestAuth, inherited, from)))
+}
+
+func (m *model) saveAuth() error {
+	auth, err := parseAuth(m.auth.Value())
+	if err != nil {
+		return fmt.Errorf("invalid auth: %w", err)
+	}
+
+	m.setRequestAuth(auth)
+	m.statusMessage = "Saved auth for the current request"
+	if auth == nil {
+		m.statusMessage = "The current request inherits its auth"
+	}
+
+	return nil
+}
+
+func (m *model) unlockVault(passphrase string) error {
+	path, err := vaultPath()
+	if err != nil {
+		return err
+	}
+
+	vault, err := openVault(path, passphrase)
+	if err != nil {
+		return err
+	}
+
+	m.vault = vault
+	m.statusMessage = "Vault unlocked"
+
+	return nil
+}
+
+// maskSecrets replaces the cleartext values of the active environment's
+// secrets in s with their {{name}} placeholder.
+func (m *model) maskSecrets(s string) string {
+	if m.vault == nil || m.activeEnvironment == nil {
+		return s
+	}
+
+	return m.vault.mask(m.activeEnvironment.Name, s)
+}
+
+// maskCollection returns a copy of c with all secret values masked.
+func (m *model) maskCollection(c *Collection) (*Collection, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	var mask func(v any) any
+	mask = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return m.maskSecrets(v)
+		case map[string]any:
+			for key, value := range v {
+				v[key] = mask(value)
+			}
+		case []any:
+			for i, value := range v {
+				v[i] = mask(value)
+			}
+		}
+
+		return v
+	}
+
+	if data, err = json.Marshal(mask(v)); err != nil {
+		return nil, err
+	}
+
+	var masked Collection
+	if err := json.Unmarshal(data, &masked); err != nil {
+		return nil, err
+	}
+
+	return &masked, nil
+}
+
+func (m *model) recordHistory() error {
+	entry := *m.inflight
+	m.inflight = nil
+
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+
+	m.history, err = appendHistory(path, m.history, entry)
+	m.historySelected = 0
+	m.renderHistory()
+
+	return err
+}
+
+// filteredHistory returns the indexes of the history entries matching the
+// filter, newest first.
+func (m *model) filteredHistory() []int {
+	var indexes []int
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if m.history[i].matches(m.historyFilter) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+func (m *model) selectedHistoryEntry() *HistoryEntry {
+	indexes := m.filteredHistory()
+	if m.historySelected < 0 || m.historySelected >= len(indexes) {
+		return nil
+	}
+
+	return &m.history[indexes[m.historySelected]]
+}
+
+func (m *model) selectHistory(selected int) {
+	m.historySelected = max(min(selected, len(m.filteredHistory())-1), 0)
+	m.renderHistory()
+	m.keepInView(m.historySelected)
+}
+
+// keepInView scrolls the response viewport so that row is visible.
+func (m *model) keepInView(row int) {
+	visible := max(m.responseView.Height-m.responseView.Style.GetVerticalFrameSize(), 1)
+	if row < m.responseView.YOffset {
+		m.responseView.SetYOffset(row)
+	} else if row >= m.responseView.YOffset+visible {
+		m.responseView.SetYOffset(row - visible + 1)
+	}
+}
+
+func (m *model) renderHistory() {
+	m.tabs[TabHistory] = "History"
+	if m.historyFilter != "" {
+		m.tabs[TabHistory] = "History: " + m.historyFilter
+	}
+
+	var b strings.Builder
+	indexes := m.filteredHistory()
+	for i, index := range indexes {
+		prefix := "  "
+		if i == m.historySelected {
+			prefix = "> "
+		}
+		b.WriteString(prefix + m.history[index].summary() + "\n")
+	}
+
+	switch {
+	case len(indexes) > 0:
+	case m.historyFilter != "":
+		fmt.Fprintf(&b, "No requests match %q", m.historyFilter)
+	default:
+		b.WriteString("No requests sent yet")
+	}
+
+	m.tabContent[TabHistory] = strings.TrimSuffix(b.String(), "\n")
+	if m.activeTab == TabHistory {
+		m.responseView.SetContent(m.tabContent[TabHistory])
+	}
+}
+
+// openHistoryEntry loads the selected history entry into the editors and
+// shows its response.
+func (m *model) openHistoryEntry() tea.Cmd {
+	entry := m.selectedHistoryEntry()
+	if entry == nil {
+		return nil
+	}
+
+	m.inputs[0].SetValue(m.maskSecrets(entry.URL))
+	m.inputs[1].SetValue(entry.Method)
+	m.requestHeaders.SetValue(m.maskSecrets(formatHeaders(entry.Headers)))
+	m.requestBody.SetValue(m.maskSecrets(entry.Body))
+	m.clearRequestState()
+	m.err = nil
+
+	cmd := m.showResponse(responseMsg{
+		responseBody:    entry.ResponseBody,
+		responseHeaders: entry.ResponseHeaders,
+		responseTime:    entry.ResponseTime,
+		statusCode:      entry.StatusCode,
+		timeline:        entry.Timeline,
+	})
+
+	m.statusMessage = "Loaded request from " + entry.Time.Local().Format(time.DateTime)
+	if entry.Truncated {
+		m.statusMessage += ", the response body was truncated"
+	}
+	if entry.Error != "" {
+		m.statusMessage += ", the request failed: " + m.maskSecrets(entry.Error)
+	}
+
+	return cmd
+}
+
+// visibleTabs returns the first and last of the tabs that fit in width next to
+// the active tab, with room for the markers of hidden tabs.
+func visibleTabs(tabs []string, active, width int) (int, int) {
+	tabWidth := func(i int) int {
+		return lipgloss.Width(tabs[i]) + 2
+	}
+	markers := func(first, last int) int {
+		w := 0
+		if first > 0 {
+			w += 2
+		}
+		if last < len(tabs)-1 {
+			w += 2
+		}
+		return w
+	}
+
+	first, last := active, active
+	used := tabWidth(active)
+	for grown := true; grown; {
+		grown = false
+		if last+1 < len(tabs) && used+tabWidth(last+1)+markers(first, last+1) <= width {
+			last++
+			used += tabWidth(last)
+			grown = true
+		}
+		if first > 0 && used+tabWidth(first-1)+markers(first-1, last) <= width {
+			first--
+			used += tabWidth(first)
+			grown = true
+		}
+	}
+
+	return first, last
+}
+
+func centered(s string, width int) string {
+	padding := max((width-len(s))/2, 0)
+	return strings.Repeat(" ", padding) + s
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(runes[:max(width, 0)])
+	}
+
+	return string(runes[:width-1]) + "…"
 }
 
 func tabBorderWithBottom(left, middle, right string) lipgloss.Border {
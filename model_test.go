@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// newTestModel returns the initial model with its config dir in a temp dir,
+// so it doesn't read or write the user's collections and history.
+func newTestModel(t *testing.T) model {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	m := initialModel()
+	if m.err != nil {
+		t.Fatalf("initialModel() error = %v", m.err)
+	}
+
+	return m
+}
+
+func TestSetWarnings(t *testing.T) {
+	m := newTestModel(t)
+	m.activeTab = TabWarnings
+
+	note := m.setWarnings("Imported shop.json", []string{"Shop > Login: scripts are not supported", "Shop > Draft: skipped, url is required"})
+	if note != ", 2 warnings, see the Warnings tab" {
+		t.Errorf("setWarnings() = %q", note)
+	}
+	if m.tabs[TabWarnings] != "Warnings: 2" {
+		t.Errorf("tab = %q, want Warnings: 2", m.tabs[TabWarnings])
+	}
+	if want := "Imported shop.json\n\n· Shop > Login: scripts are not supported\n· Shop > Draft: skipped, url is required"; m.tabContent[TabWarnings] != want {
+		t.Errorf("tab content = %q, want %q", m.tabContent[TabWarnings], want)
+	}
+	if !strings.Contains(m.responseView.View(), "Shop > Draft: skipped") {
+		t.Errorf("response view doesn't show the active Warnings tab")
+	}
+
+	if note := m.setWarnings("Pasted curl GET https://example.com", nil); note != "" || m.tabs[TabWarnings] != "Warnings" || m.tabContent[TabWarnings] != noWarnings {
+		t.Errorf("setWarnings(nil) = %q, tab %q %q", note, m.tabs[TabWarnings], m.tabContent[TabWarnings])
+	}
+}
+
+// runCmd runs cmd and the commands it batches, and sends their messages to
+// the returned channel.
+func runCmd(cmd tea.Cmd) <-chan tea.Msg {
+	msgs := make(chan tea.Msg, 8)
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, cmd := range batch {
+				go run(cmd)
+			}
+			return
+		}
+		msgs <- msg
+	}
+	go run(cmd)
+
+	return msgs
+}
+
+func TestCancelRequest(t *testing.T) {
+	received := make(chan struct{})
+	aborted := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	m := newTestModel(t)
+	m.inputs[0].SetValue(srv.URL)
+	m.inputs[1].SetValue("GET")
+	msgs := runCmd(m.run())
+	sentID := m.requestID
+	if !m.startSpinner {
+		t.Fatal("run() didn't start the request")
+	}
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server didn't receive the request")
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if !m.cancelled || m.startSpinner || m.requestID == sentID {
+		t.Errorf("after esc cancelled = %v, spinner = %v, request id = %d", m.cancelled, m.startSpinner, m.requestID)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancel didn't abort the context of the request in flight")
+	}
+
+	// The error of the aborted request is ignored
+	for msg := range msgs {
+		if msg, ok := msg.(errMsg); ok {
+			if msg.requestID != sentID {
+				t.Fatalf("errMsg request id = %d, want %d", msg.requestID, sentID)
+			}
+			updated, _ = m.Update(msg)
+			m = updated.(model)
+			break
+		}
+	}
+	if m.err != nil {
+		t.Errorf("error of the cancelled request = %v, want it ignored", m.err)
+	}
+
+	// So is a response that made it before the cancel
+	updated, _ = m.Update(responseMsg{requestID: sentID, statusCode: 200, responseBody: `{"stale": true}`})
+	m = updated.(model)
+	if m.statusCode != 0 || m.responseBody != "" || strings.Contains(m.tabContent[TabResponseBody], "stale") {
+		t.Errorf("stale response shown: status %d, body %q", m.statusCode, m.responseBody)
+	}
+	if len(m.history) != 1 || m.history[0].Error != "cancelled" {
+		t.Errorf("history = %+v, want one cancelled entry", m.history)
+	}
+}
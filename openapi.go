@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maxSchemaDepth = 8
+
+var (
+	openAPIMethods      = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	openAPIPathTemplate = regexp.MustCompile(`\{([^{}]+)\}`)
+)
+
+type openAPIImporter struct {
+	doc      map[string]any
+	warnings []string
+	security map[string]bool
+}
+
+// parseSpecDocument decodes a JSON or YAML OpenAPI document into generic maps.
+func parseSpecDocument(data []byte) (map[string]any, error) {
+	var doc map[string]any
+	if json.Valid(data) {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func isOpenAPISpec(doc map[string]any) bool {
+	_, openapi := doc["openapi"]
+	_, swagger := doc["swagger"]
+
+	return openapi || swagger
+}
+
+// importOpenAPI generates a collection from an OpenAPI 3.x or Swagger 2.0
+// document with one folder per tag and one request per operation.
+func importOpenAPI(doc map[string]any) (*Collection, []string, error) {
+	imp := &openAPIImporter{doc: doc, security: map[string]bool{}}
+
+	swagger, openapi := specVersion(doc["swagger"]), specVersion(doc["openapi"])
+	if major, _, _ := strings.Cut(swagger, "."); swagger != "" && major != "2" {
+		return nil, nil, fmt.Errorf("unsupported swagger version %q", swagger)
+	}
+	if major, _, _ := strings.Cut(openapi, "."); openapi != "" && major != "3" {
+		return nil, nil, fmt.Errorf("unsupported openapi version %q", openapi)
+	}
+
+	baseURL := imp.baseURL()
+	if u, err := url.Parse(baseURL); err != nil || u.Host == "" {
+		imp.warnings = append(imp.warnings, "no server URL, set the baseUrl variable")
+	}
+
+	info := mapValue(doc["info"])
+	c := &Collection{
+		Name:        stringValue(info["title"]),
+		Description: stringValue(info["description"]),
+		Variables:   map[string]string{"baseUrl": baseURL},
+	}
+
+	folders := map[string]*Folder{}
+	tagDescriptions := map[string]string{}
+	for _, tag := range sliceValue(doc["tags"]) {
+		tag := mapValue(tag)
+		tagDescriptions[stringValue(tag["name"])] = stringValue(tag["description"])
+	}
+
+	paths := mapValue(doc["paths"])
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		pathItem := imp.resolve(mapValue(paths[path]))
+		for _, method := range openAPIMethods {
+			operation, ok := pathItem[method].(map[string]any)
+			if !ok {
+				continue
+			}
+
+			r := imp.convertOperation(strings.ToUpper(method), path, pathItem, operation)
+
+			tags := sliceValue(operation["tags"])
+			if len(tags) == 0 {
+				c.Requests = append(c.Requests, r)
+				continue
+			}
+
+			tag := stringValue(tags[0])
+			folder, ok := folders[tag]
+			if !ok {
+				folder = &Folder{Name: tag, Description: tagDescriptions[tag]}
+				folders[tag] = folder
+				c.Folders = append(c.Folders, folder)
+			}
+			folder.Requests = append(folder.Requests, r)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(imp.security)) {
+		c.Variables[name] = ""
+	}
+
+	slices.Sort(imp.warnings)
+
+	return c, slices.Compact(imp.warnings), c.Validate()
+}
+
+func (imp *openAPIImporter) baseURL() string {
+	if servers := sliceValue(imp.doc["servers"]); len(servers) > 0 {
+		server := mapValue(servers[0])
+		serverUrl := stringValue(server["url"])
+		variables := mapValue(server["variables"])
+		serverUrl = openAPIPathTemplate.ReplaceAllStringFunc(serverUrl, func(v string) string {
+			return stringValue(mapValue(variables[v[1:len(v)-1]])["default"])
+		})
+
+		return strings.TrimSuffix(serverUrl, "/")
+	}
+
+	host := stringValue(imp.doc["host"])
+	if host == "" {
+		return strings.TrimSuffix(stringValue(imp.doc["basePath"]), "/")
+	}
+
+	scheme := "https"
+	if schemes := sliceValue(imp.doc["schemes"]); len(schemes) > 0 && !slices.Contains(schemes, any("https")) {
+		scheme = stringValue(schemes[0])
+	}
+	base := url.URL{Scheme: scheme, Host: host, Path: stringValue(imp.doc["basePath"])}
+
+	return strings.TrimSuffix(base.String(), "/")
+}
+
+func (imp *openAPIImporter) convertOperation(method, path string, pathItem, operation map[string]any) *Request {
+	r := &Request{
+		Name:        stringValue(operation["summary"]),
+		Description: stringValue(operation["description"]),
+		Method:      method,
+		URL:         "{{baseUrl}}" + openAPIPathTemplate.ReplaceAllString(path, "{{$1}}"),
+	}
+	if r.Name == "" {
+		r.Name = stringValue(operation["operationId"])
+	}
+	if r.Name == "" {
+		r.Name = method + " " + path
+	}
+
+	// Operation parameters override path item parameters with the same name
+	parameters := map[string]map[string]any{}
+	var order []string
+	for _, p := range append(sliceValue(pathItem["parameters"]), sliceValue(operation["parameters"])...) {
+		param := imp.resolve(mapValue(p))
+		id := stringValue(param["in"]) + ":" + stringValue(param["name"])
+		if _, ok := parameters[id]; !ok {
+			order = append(order, id)
+		}
+		parameters[id] = param
+	}
+
+	formData := url.Values{}
+	for _, id := range order {
+		param := parameters[id]
+		name := stringValue(param["name"])
+		required, _ := param["required"].(bool)
+		value, hasValue := imp.parameterExample(param)
+
+		switch stringValue(param["in"]) {
+		case "query":
+			if required || hasValue {
+				r.addQuery(name, value)
+			}
+		case "header":
+			if required || hasValue {
+				r.setHeader(name, value)
+			}
+		case "body":
+			r.ContentType = firstString(operation["consumes"], imp.doc["consumes"], "application/json")
+			r.Body = imp.formatExample(imp.exampleFromSchema(mapValue(param["schema"]), 0), r.ContentType)
+		case "formData":
+			formData.Set(name, value)
+		}
+	}
+
+	if len(formData) > 0 {
+		r.ContentType = firstString(operation["consumes"], imp.doc["consumes"], "application/x-www-form-urlencoded")
+		if strings.Contains(r.ContentType, "multipart") {
+			imp.warnings = append(imp.warnings, fmt.Sprintf("%s %s: multipart form body is not supported", method, path))
+		} else {
+			r.Body = templateUnescaper.Replace(formData.Encode())
+		}
+	}
+
+	if requestBody := imp.resolve(mapValue(operation["requestBody"])); len(requestBody) > 0 {
+		imp.convertRequestBody(r, requestBody)
+	}
+
+	imp.applySecurity(r, operation)
+
+	return r
+}
+
+func (imp *openAPIImporter) convertRequestBody(r *Request, requestBody map[string]any) {
+	content := mapValue(requestBody["content"])
+	if len(content) == 0 {
+		return
+	}
+
+	contentType := "application/json"
+	if _, ok := content[contentType]; !ok {
+		contentType = slices.Sorted(maps.Keys(content))[0]
+	}
+	media := mapValue(content[contentType])
+	r.ContentType = contentType
+
+	if strings.Contains(contentType, "multipart") {
+		imp.warnings = append(imp.warnings, fmt.Sprintf("%s %s: multipart request body is not supported", r.Method, r.URL))
+		return
+	}
+
+	var example any
+	if e, ok := media["example"]; ok {
+		example = e
+	} else if examples := mapValue(media["examples"]); len(examples) > 0 {
+		first := imp.resolve(mapValue(examples[slices.Sorted(maps.Keys(examples))[0]]))
+		example = first["value"]
+	} else {
+		example = imp.exampleFromSchema(mapValue(media["schema"]), 0)
+	}
+
+	r.Body = imp.formatExample(example, contentType)
+}
+
+func (imp *openAPIImporter) applySecurity(r *Request, operation map[string]any) {
+	requirements, ok := operation["security"].([]any)
+	if !ok {
+		requirements = sliceValue(imp.doc["security"])
+	}
+	if len(requirements) == 0 {
+		return
+	}
+
+	schemes := mapValue(mapValue(imp.doc["components"])["securitySchemes"])
+	if len(schemes) == 0 {
+		schemes = mapValue(imp.doc["securityDefinitions"])
+	}
+
+	// Only the first alternative of the requirements has to be satisfied
+	for _, name := range slices.Sorted(maps.Keys(mapValue(requirements[0]))) {
+		scheme := imp.resolve(mapValue(schemes[name]))
+		variable := name
+		switch {
+		case stringValue(scheme["type"]) == "apiKey" && stringValue(scheme["in"]) == "header":
+			r.setHeader(stringValue(scheme["name"]), "{{"+variable+"}}")
+		case stringValue(scheme["type"]) == "apiKey" && stringValue(scheme["in"]) == "query":
+			r.addQuery(stringValue(scheme["name"]), "{{"+variable+"}}")
+		case strings.EqualFold(stringValue(scheme["scheme"]), "bearer"), stringValue(scheme["type"]) == "oauth2", stringValue(scheme["type"]) == "openIdConnect":
+			r.setHeader("Authorization", "Bearer {{"+variable+"}}")
+		case strings.EqualFold(stringValue(scheme["scheme"]), "basic"), stringValue(scheme["type"]) == "basic":
+			r.setHeader("Authorization", "Basic {{"+variable+"}}")
+		default:
+			imp.warnings = append(imp.warnings, fmt.Sprintf("%s %s: security scheme %q is not supported", r.Method, r.URL, name))
+			continue
+		}
+		imp.security[variable] = true
+	}
+}
+
+func (imp *openAPIImporter) parameterExample(param map[string]any) (string, bool) {
+	for _, key := range []string{"example", "default"} {
+		if value, ok := param[key]; ok {
+			return fmt.Sprint(value), true
+		}
+	}
+
+	schema := imp.resolve(mapValue(param["schema"]))
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return fmt.Sprint(value), true
+		}
+	}
+	if enum := sliceValue(schema["enum"]); len(enum) > 0 {
+		return fmt.Sprint(enum[0]), true
+	}
+	if enum := sliceValue(param["enum"]); len(enum) > 0 {
+		return fmt.Sprint(enum[0]), true
+	}
+
+	return "{{" + stringValue(param["name"]) + "}}", false
+}
+
+// exampleFromSchema builds an example value from the schema's examples,
+// defaults and types. Recursive references are left out of the example.
+func (imp *openAPIImporter) exampleFromSchema(schema map[string]any, depth int, refs ...string) any {
+	if depth > maxSchemaDepth {
+		return nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if slices.Contains(refs, ref) {
+			return nil
+		}
+		refs = append(refs, ref)
+	}
+	schema = imp.resolve(schema)
+
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if enum := sliceValue(schema["enum"]); len(enum) > 0 {
+		return enum[0]
+	}
+
+	if allOf := sliceValue(schema["allOf"]); len(allOf) > 0 {
+		merged := map[string]any{}
+		for _, sub := range allOf {
+			if object, ok := imp.exampleFromSchema(mapValue(sub), depth+1, refs...).(map[string]any); ok {
+				maps.Copy(merged, object)
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives := sliceValue(schema[key]); len(alternatives) > 0 {
+			return imp.exampleFromSchema(mapValue(alternatives[0]), depth+1, refs...)
+		}
+	}
+
+	schemaType := stringValue(schema["type"])
+	if types := sliceValue(schema["type"]); len(types) > 0 {
+		schemaType = stringValue(types[0])
+	}
+	if schemaType == "" && schema["properties"] != nil {
+		schemaType = "object"
+	}
+
+	switch schemaType {
+	case "object":
+		object := map[string]any{}
+		properties := mapValue(schema["properties"])
+		for _, name := range slices.Sorted(maps.Keys(properties)) {
+			if value := imp.exampleFromSchema(mapValue(properties[name]), depth+1, refs...); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	case "array":
+		if item := imp.exampleFromSchema(mapValue(schema["items"]), depth+1, refs...); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		switch stringValue(schema["format"]) {
+		case "date-time":
+			return "1970-01-01T00:00:00Z"
+		case "date":
+			return "1970-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	}
+
+	return nil
+}
+
+func (imp *openAPIImporter) formatExample(example any, contentType string) string {
+	if s, ok := example.(string); ok {
+		return s
+	}
+
+	if strings.Contains(contentType, "x-www-form-urlencoded") {
+		if object, ok := example.(map[string]any); ok {
+			form := url.Values{}
+			for key, value := range object {
+				form.Set(key, fmt.Sprint(value))
+			}
+			return form.Encode()
+		}
+	}
+
+	data, err := json.MarshalIndent(example, "", "  ")
+	if err != nil {
+		imp.warnings = append(imp.warnings, fmt.Sprintf("could not render example body: %v", err))
+		return ""
+	}
+
+	return string(data)
+}
+
+// resolve follows local "#/..." references, reporting those it cannot find.
+func (imp *openAPIImporter) resolve(node map[string]any) map[string]any {
+	for range maxSchemaDepth {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+
+		if !strings.HasPrefix(ref, "#/") {
+			imp.warnings = append(imp.warnings, fmt.Sprintf("external reference %q is not supported", ref))
+			return map[string]any{}
+		}
+
+		var target any = imp.doc
+		for part := range strings.SplitSeq(ref[2:], "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			target = mapValue(target)[part]
+		}
+
+		resolved, ok := target.(map[string]any)
+		if !ok {
+			imp.warnings = append(imp.warnings, fmt.Sprintf("reference %q not found", ref))
+			return map[string]any{}
+		}
+		node = resolved
+	}
+
+	return node
+}
+
+func firstString(values ...any) string {
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			return v
+		case []any:
+			if len(v) > 0 {
+				return stringValue(v[0])
+			}
+		}
+	}
+
+	return ""
+}
+
+func mapValue(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func sliceValue(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+// specVersion returns the swagger or openapi version of a document, which
+// YAML decodes as a number when it is not quoted.
+func specVersion(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestImportOpenAPIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{"openapi string", "openapi: \"3.0.3\"\nservers: [{url: https://api.example.com}]", ""},
+		{"openapi number", "openapi: 3.0\nservers: [{url: https://api.example.com}]", ""},
+		{"openapi 3.1 number", "openapi: 3.1\nservers: [{url: https://api.example.com}]", ""},
+		{"swagger number", "swagger: 2.0\nhost: api.example.com", ""},
+		{"swagger string", "swagger: \"2.0\"\nhost: api.example.com", ""},
+		{"openapi 4", "openapi: 4.0.0", `unsupported openapi version "4.0.0"`},
+		{"swagger 1.2", "swagger: 1.2", `unsupported swagger version "1.2"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseSpecDocument([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("parseSpecDocument() error = %v", err)
+			}
+			if !isOpenAPISpec(doc) {
+				t.Fatalf("isOpenAPISpec() = false")
+			}
+
+			_, _, err = importOpenAPI(doc)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("importOpenAPI() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("importOpenAPI() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportOpenAPIBaseURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		want        string
+		wantWarning bool
+	}{
+		{"server", `{"openapi": "3.0.0", "servers": [{"url": "https://api.example.com/v1/"}]}`, "https://api.example.com/v1", false},
+		{
+			"server variables",
+			`{"openapi": "3.0.0", "servers": [{"url": "https://{region}.example.com", "variables": {"region": {"default": "eu"}}}]}`,
+			"https://eu.example.com", false,
+		},
+		{"no servers", `{"openapi": "3.0.0"}`, "", true},
+		{"relative server", `{"openapi": "3.0.0", "servers": [{"url": "/v1"}]}`, "/v1", true},
+		{"swagger host", `{"swagger": "2.0", "host": "api.example.com", "basePath": "/v2", "schemes": ["http", "https"]}`, "https://api.example.com/v2", false},
+		{"swagger http", `{"swagger": "2.0", "host": "api.example.com", "schemes": ["http"]}`, "http://api.example.com", false},
+		{"swagger no host", `{"swagger": "2.0", "basePath": "/v2"}`, "/v2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseSpecDocument([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("parseSpecDocument() error = %v", err)
+			}
+
+			c, warnings, err := importOpenAPI(doc)
+			if err != nil {
+				t.Fatalf("importOpenAPI() error = %v", err)
+			}
+			if got := c.Variables["baseUrl"]; got != tt.want {
+				t.Errorf("baseUrl = %q, want %q", got, tt.want)
+			}
+			if got := slices.ContainsFunc(warnings, func(w string) bool { return strings.Contains(w, "baseUrl") }); got != tt.wantWarning {
+				t.Errorf("warnings = %q, want a baseUrl warning: %v", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestImportOpenAPIOperations(t *testing.T) {
+	spec := `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com
+tags:
+  - name: pets
+    description: Pet operations
+security:
+  - apiKey: []
+components:
+  securitySchemes:
+    apiKey: {type: apiKey, in: header, name: X-Api-Key}
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string, example: Rex}
+        age: {type: integer}
+paths:
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true}
+    get:
+      tags: [pets]
+      summary: Get a pet
+      parameters:
+        - {name: verbose, in: query, schema: {type: boolean, default: false}}
+        - {name: X-Trace, in: header}
+  /pets:
+    post:
+      tags: [pets]
+      operationId: createPet
+      security: []
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+  /upload:
+    put:
+      requestBody:
+        content:
+          multipart/form-data: {}
+`
+	doc, err := parseSpecDocument([]byte(spec))
+	if err != nil {
+		t.Fatalf("parseSpecDocument() error = %v", err)
+	}
+	c, warnings, err := importOpenAPI(doc)
+	if err != nil {
+		t.Fatalf("importOpenAPI() error = %v", err)
+	}
+
+	if len(c.Folders) != 1 || c.Folders[0].Name != "pets" || c.Folders[0].Description != "Pet operations" {
+		t.Fatalf("folders = %+v, want one pets folder", c.Folders)
+	}
+	requests := c.Folders[0].Requests
+	if len(requests) != 2 {
+		t.Fatalf("pets requests = %d, want 2", len(requests))
+	}
+
+	create := requests[0]
+	if create.Name != "createPet" || create.Method != "POST" || create.URL != "{{baseUrl}}/pets" {
+		t.Errorf("create = %s %s %q", create.Method, create.URL, create.Name)
+	}
+	if create.ContentType != "application/json" || !strings.Contains(create.Body, `"name": "Rex"`) || !strings.Contains(create.Body, `"age": 0`) {
+		t.Errorf("create body = %q %q", create.ContentType, create.Body)
+	}
+	if create.Headers["X-Api-Key"] != "" {
+		t.Errorf("create headers = %v, want no api key with an empty security requirement", create.Headers)
+	}
+
+	get := requests[1]
+	if get.Name != "Get a pet" || get.URL != "{{baseUrl}}/pets/{{petId}}" {
+		t.Errorf("get = %s %q", get.URL, get.Name)
+	}
+	if got := get.Query.Get("verbose"); got != "false" {
+		t.Errorf("get query verbose = %q, want false", got)
+	}
+	if _, ok := get.Headers["X-Trace"]; ok {
+		t.Errorf("get headers = %v, want no optional header without an example", get.Headers)
+	}
+	if got := get.Headers["X-Api-Key"]; got != "{{apiKey}}" {
+		t.Errorf("get api key header = %q, want {{apiKey}}", got)
+	}
+	if _, ok := c.Variables["apiKey"]; !ok {
+		t.Errorf("variables = %v, want apiKey", c.Variables)
+	}
+
+	if len(c.Requests) != 1 || c.Requests[0].Method != "PUT" {
+		t.Fatalf("untagged requests = %+v, want the upload", c.Requests)
+	}
+	if want := "PUT {{baseUrl}}/upload: multipart request body is not supported"; !slices.Contains(warnings, want) {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var postmanPathVariable = regexp.MustCompile(`(^|/):([A-Za-z0-9_]+)`)
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+	Event    []json.RawMessage `json:"event,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string             `json:"name"`
+	Description postmanDescription `json:"description,omitempty"`
+	Schema      string             `json:"schema"`
+}
+
+type postmanItem struct {
+	Name        string             `json:"name"`
+	Description postmanDescription `json:"description,omitempty"`
+	Item        []postmanItem      `json:"item,omitempty"`
+	Request     *postmanRequest    `json:"request,omitempty"`
+	Auth        *postmanAuth       `json:"auth,omitempty"`
+	Event       []json.RawMessage  `json:"event,omitempty"`
+	Response    []json.RawMessage  `json:"response,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string             `json:"method"`
+	Header      []postmanKeyValue  `json:"header,omitempty"`
+	URL         postmanURL         `json:"url"`
+	Body        *postmanBody       `json:"body,omitempty"`
+	Auth        *postmanAuth       `json:"auth,omitempty"`
+	Description postmanDescription `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string              `json:"mode"`
+	Raw        string              `json:"raw,omitempty"`
+	URLEncoded []postmanKeyValue   `json:"urlencoded,omitempty"`
+	FormData   []postmanKeyValue   `json:"formdata,omitempty"`
+	GraphQL    *postmanGraphQL     `json:"graphql,omitempty"`
+	Options    *postmanBodyOptions `json:"options,omitempty"`
+}
+
+type postmanBodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type postmanGraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables,omitempty"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Basic  []postmanKeyValue `json:"basic,omitempty"`
+	Bearer []postmanKeyValue `json:"bearer,omitempty"`
+	APIKey []postmanKeyValue `json:"apikey,omitempty"`
+	Digest []postmanKeyValue `json:"digest,omitempty"`
+	OAuth2 []postmanKeyValue `json:"oauth2,omitempty"`
+	AWSv4  []postmanKeyValue `json:"awsv4,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// postmanDescription is either a plain string or a {"content": ...} object.
+type postmanDescription string
+
+func (d *postmanDescription) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*d = postmanDescription(s)
+		return nil
+	}
+
+	var object struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*d = postmanDescription(object.Content)
+
+	return nil
+}
+
+// postmanURL is either the raw url string or a structured url object.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		u.Raw = s
+		return nil
+	}
+
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+func (kv postmanKeyValue) String() string {
+	if kv.Value == nil {
+		return ""
+	}
+	if s, ok := kv.Value.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(kv.Value)
+}
+
+func isPostmanCollection(data []byte) bool {
+	var probe struct {
+		Info struct {
+			Schema string `json:"schema"`
+		} `json:"info"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+
+	return strings.Contains(probe.Info.Schema, "schema.getpostman.com")
+}
+
+// importPostman converts a Postman v2.1 collection export. Items that cannot
+// be represented are reported as warnings instead of failing the import.
+func importPostman(data []byte) (*Collection, []string, error) {
+	var pc postmanCollection
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []string
+	if !strings.Contains(pc.Info.Schema, "v2.1") && !strings.Contains(pc.Info.Schema, "v2.0") {
+		warnings = append(warnings, fmt.Sprintf("schema %q is not v2.1, results may be incomplete", pc.Info.Schema))
+	}
+
+	c := &Collection{
+		Name:        pc.Info.Name,
+		Description: string(pc.Info.Description),
+	}
+
+	for _, v := range pc.Variable {
+		if c.Variables == nil {
+			c.Variables = map[string]string{}
+		}
+		c.Variables[v.Key] = v.String()
+	}
+
+	if len(pc.Event) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s: scripts are not supported", c.Name))
+	}
+
+	c.Auth = convertPostmanAuth(pc.Auth, c.Name, &warnings)
+	c.Folders, c.Requests = convertPostmanItems(pc.Item, c.Name, &warnings)
+
+	return c, warnings, c.Validate()
+}
+
+func convertPostmanItems(items []postmanItem, parent string, warnings *[]string) ([]*Folder, []*Request) {
+	var folders []*Folder
+	var requests []*Request
+	for _, item := range items {
+		itemPath := parent + " > " + item.Name
+		if len(item.Event) > 0 {
+			*warnings = append(*warnings, fmt.Sprintf("%s: scripts are not supported", itemPath))
+		}
+
+		if item.Request == nil {
+			f := &Folder{
+				Name:        cmp.Or(strings.TrimSpace(item.Name), "Unnamed folder"),
+				Description: string(item.Description),
+				Auth:        convertPostmanAuth(item.Auth, itemPath, warnings),
+			}
+			f.Folders, f.Requests = convertPostmanItems(item.Item, itemPath, warnings)
+			folders = append(folders, f)
+			continue
+		}
+
+		if len(item.Response) > 0 {
+			*warnings = append(*warnings, fmt.Sprintf("%s: %d saved example responses were skipped", itemPath, len(item.Response)))
+		}
+
+		// An item that can't be sent is skipped, not the whole collection
+		r := convertPostmanRequest(item, itemPath, warnings)
+		if err := r.Validate(); err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("%s: skipped, %v", itemPath, err))
+			continue
+		}
+		requests = append(requests, r)
+	}
+
+	return folders, requests
+}
+
+func convertPostmanRequest(item postmanItem, itemPath string, warnings *[]string) *Request {
+	pr := item.Request
+	r := &Request{
+		Name:        item.Name,
+		Description: string(pr.Description),
+		Method:      strings.ToUpper(pr.Method),
+	}
+	if r.Description == "" {
+		r.Description = string(item.Description)
+	}
+	if r.Method == "" {
+		r.Method = "GET"
+	}
+
+	rawUrl, rawQuery, _ := strings.Cut(pr.URL.Raw, "?")
+	rawQuery, _, _ = strings.Cut(rawQuery, "#")
+	r.URL = postmanPathVariable.ReplaceAllStringFunc(rawUrl, func(segment string) string {
+		prefix, name, _ := strings.Cut(segment, ":")
+		for _, v := range pr.URL.Variable {
+			if v.Key == name && v.String() != "" {
+				return prefix + v.String()
+			}
+		}
+
+		return prefix + "{{" + name + "}}"
+	})
+
+	if len(pr.URL.Query) > 0 {
+		for _, q := range pr.URL.Query {
+			if !q.Disabled {
+				r.addQuery(q.Key, q.String())
+			}
+		}
+	} else if rawQuery != "" {
+		// Keep {{variables}} intact instead of using url.ParseQuery
+		for pair := range strings.SplitSeq(rawQuery, "&") {
+			key, value, _ := strings.Cut(pair, "=")
+			r.addQuery(queryUnescape(key), queryUnescape(value))
+		}
+	}
+
+	for _, h := range pr.Header {
+		if !h.Disabled {
+			r.setHeader(h.Key, h.String())
+		}
+	}
+
+	if pr.Body != nil {
+		convertPostmanBody(r, pr.Body, itemPath, warnings)
+	}
+
+	r.Auth = convertPostmanAuth(pr.Auth, itemPath, warnings)
+
+	if r.ContentType == "" {
+		r.ContentType = detectContentType(headerValue(r.Headers, "Content-Type"), r.Body)
+	}
+
+	return r
+}
+
+func convertPostmanBody(r *Request, body *postmanBody, itemPath string, warnings *[]string) {
+	switch body.Mode {
+	case "", "none":
+	case "raw":
+		r.Body = body.Raw
+		if body.Options != nil {
+			switch body.Options.Raw.Language {
+			case "json":
+				r.ContentType = "application/json"
+			case "xml":
+				r.ContentType = "application/xml"
+			case "html":
+				r.ContentType = "text/html"
+			case "javascript":
+				r.ContentType = "application/javascript"
+			case "text":
+				r.ContentType = "text/plain"
+			}
+		}
+	case "urlencoded":
+		var pairs []string
+		for _, kv := range body.URLEncoded {
+			if !kv.Disabled {
+				pairs = append(pairs, templateUnescaper.Replace(url.QueryEscape(kv.Key)+"="+url.QueryEscape(kv.String())))
+			}
+		}
+		r.Body = strings.Join(pairs, "&")
+		r.ContentType = "application/x-www-form-urlencoded"
+	case "graphql":
+		if body.GraphQL == nil {
+			return
+		}
+		payload := map[string]any{"query": body.GraphQL.Query}
+		if strings.TrimSpace(body.GraphQL.Variables) != "" {
+			payload["variables"] = json.RawMessage(body.GraphQL.Variables)
+		}
+		data, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			*warnings = append(*warnings, fmt.Sprintf("%s: invalid graphql variables: %v", itemPath, err))
+			return
+		}
+		r.Body = string(data)
+		r.ContentType = "application/json"
+	default:
+		*warnings = append(*warnings, fmt.Sprintf("%s: %s body is not supported", itemPath, body.Mode))
+	}
+}
+
+// convertPostmanAuth converts the auth set on a collection, folder or request.
+// Postman leaves auth out where it is inherited, just like postui.
+func convertPostmanAuth(auth *postmanAuth, itemPath string, warnings *[]string) *Auth {
+	if auth == nil {
+		return nil
+	}
+
+	param := func(params []postmanKeyValue, key string) string {
+		for _, p := range params {
+			if p.Key == key {
+				return p.String()
+			}
+		}
+
+		return ""
+	}
+
+	var a *Auth
+	switch auth.Type {
+	case "", "noauth":
+		return &Auth{Type: AuthNone}
+	case "bearer":
+		a = &Auth{Type: AuthBearer, Token: param(auth.Bearer, "token")}
+	case "basic":
+		a = &Auth{Type: AuthBasic, Username: param(auth.Basic, "username"), Password: param(auth.Basic, "password")}
+	case "digest":
+		a = &Auth{Type: AuthDigest, Username: param(auth.Digest, "username"), Password: param(auth.Digest, "password")}
+	case "apikey":
+		a = &Auth{Type: AuthAPIKey, Key: param(auth.APIKey, "key"), Value: param(auth.APIKey, "value")}
+		if param(auth.APIKey, "in") == "query" {
+			a.In = "query"
+		}
+	case "oauth2":
+		a = &Auth{
+			Type:         AuthOAuth2,
+			TokenURL:     param(auth.OAuth2, "accessTokenUrl"),
+			ClientID:     param(auth.OAuth2, "clientId"),
+			ClientSecret: param(auth.OAuth2, "clientSecret"),
+			Scope:        param(auth.OAuth2, "scope"),
+		}
+		switch param(auth.OAuth2, "grant_type") {
+		case "client_credentials":
+			a.Grant = grantClientCredentials
+		case "password_credentials":
+			a.Grant = grantPassword
+			a.Username, a.Password = param(auth.OAuth2, "username"), param(auth.OAuth2, "password")
+		default:
+			*warnings = append(*warnings, fmt.Sprintf("%s: oauth2 %s grant is not supported", itemPath, param(auth.OAuth2, "grant_type")))
+			return nil
+		}
+	case "awsv4":
+		a = &Auth{
+			Type:         AuthAWSv4,
+			AccessKey:    param(auth.AWSv4, "accessKey"),
+			SecretKey:    param(auth.AWSv4, "secretKey"),
+			SessionToken: param(auth.AWSv4, "sessionToken"),
+			Region:       param(auth.AWSv4, "region"),
+			Service:      param(auth.AWSv4, "service"),
+		}
+	default:
+		*warnings = append(*warnings, fmt.Sprintf("%s: %s auth is not supported", itemPath, auth.Type))
+		return nil
+	}
+
+	if err := a.Validate(); err != nil {
+		*warnings = append(*warnings, fmt.Sprintf("%s: %v", itemPath, err))
+		return nil
+	}
+
+	return a
+}
+
+func queryUnescape(s string) string {
+	unescaped, err := url.QueryUnescape(s)
+	if err != nil {
+		return s
+	}
+
+	return unescaped
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+const postmanTestCollection = `{
+  "info": {
+    "name": "Shop",
+    "description": {"content": "The shop API"},
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "variable": [{"key": "baseUrl", "value": "https://shop.example.com"}, {"key": "page", "value": 1}],
+  "item": [
+    {
+      "name": "Users",
+      "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "secret"}]},
+      "item": [
+        {
+          "name": "Get user",
+          "request": {
+            "method": "get",
+            "url": {
+              "raw": "{{baseUrl}}/users/:id?verbose=true&skip=1",
+              "query": [{"key": "verbose", "value": "true"}, {"key": "skip", "value": "1", "disabled": true}],
+              "variable": [{"key": "id", "value": "42"}]
+            },
+            "header": [{"key": "Accept", "value": "application/json"}, {"key": "X-Debug", "value": "1", "disabled": true}]
+          },
+          "response": [{}, {}]
+        },
+        {
+          "name": "Create user",
+          "event": [{"listen": "test"}],
+          "request": {
+            "method": "POST",
+            "url": "{{baseUrl}}/users/:org/members?tag=a%20b&name={{name}}",
+            "body": {"mode": "raw", "raw": "<user/>", "options": {"raw": {"language": "xml"}}},
+            "auth": {"type": "noauth"}
+          }
+        }
+      ]
+    },
+    {
+      "name": "Login",
+      "request": {
+        "method": "POST",
+        "url": "{{baseUrl}}/login",
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "{{user}}"}, {"key": "pass word", "value": "a&b"}]},
+        "auth": {"type": "oauth2", "oauth2": [{"key": "grant_type", "value": "authorization_code"}]}
+      }
+    },
+    {
+      "name": "Search",
+      "request": {
+        "method": "POST",
+        "url": "{{baseUrl}}/graphql",
+        "body": {"mode": "graphql", "graphql": {"query": "{ users { id } }", "variables": "{\"first\": 2}"}}
+      }
+    },
+    {
+      "name": "Upload",
+      "request": {"method": "PUT", "url": "{{baseUrl}}/files", "body": {"mode": "file"}}
+    },
+    {
+      "name": "Draft",
+      "request": {"method": "GET", "url": ""}
+    },
+    {
+      "name": "Health",
+      "request": {"method": "GET", "url": "shop.example.com/health"}
+    }
+  ]
+}`
+
+func TestImportPostman(t *testing.T) {
+	data := []byte(postmanTestCollection)
+	if !isPostmanCollection(data) {
+		t.Fatal("isPostmanCollection() = false")
+	}
+
+	c, warnings, err := importPostman(data)
+	if err != nil {
+		t.Fatalf("importPostman() error = %v", err)
+	}
+
+	if c.Name != "Shop" || c.Description != "The shop API" {
+		t.Errorf("collection = %q %q", c.Name, c.Description)
+	}
+	if c.Variables["baseUrl"] != "https://shop.example.com" || c.Variables["page"] != "1" {
+		t.Errorf("variables = %v", c.Variables)
+	}
+	if c.Auth == nil || c.Auth.Type != AuthBearer || c.Auth.Token != "{{token}}" {
+		t.Errorf("collection auth = %+v", c.Auth)
+	}
+
+	if len(c.Folders) != 1 || c.Folders[0].Name != "Users" || len(c.Folders[0].Requests) != 2 {
+		t.Fatalf("folders = %+v, want Users with 2 requests", c.Folders)
+	}
+	users := c.Folders[0]
+	if users.Auth == nil || users.Auth.Type != AuthBasic || users.Auth.Username != "admin" || users.Auth.Password != "secret" {
+		t.Errorf("folder auth = %+v", users.Auth)
+	}
+
+	get := users.Requests[0]
+	if get.Method != "GET" || get.URL != "{{baseUrl}}/users/42" || get.Query.Encode() != "verbose=true" {
+		t.Errorf("get = %s %s ? %s", get.Method, get.URL, get.Query.Encode())
+	}
+	if len(get.Headers) != 1 || get.Headers["Accept"] != "application/json" {
+		t.Errorf("get headers = %v, want Accept only", get.Headers)
+	}
+
+	create := users.Requests[1]
+	if create.URL != "{{baseUrl}}/users/{{org}}/members" || create.Query.Get("tag") != "a b" || create.Query.Get("name") != "{{name}}" {
+		t.Errorf("create = %s ? %v", create.URL, create.Query)
+	}
+	if create.Body != "<user/>" || create.ContentType != "application/xml" {
+		t.Errorf("create body = %q %q", create.ContentType, create.Body)
+	}
+	if create.Auth == nil || create.Auth.Type != AuthNone {
+		t.Errorf("create auth = %+v, want none", create.Auth)
+	}
+
+	if len(c.Requests) != 3 {
+		t.Errorf("requests = %d, want 3 without the skipped ones", len(c.Requests))
+	}
+	requests := map[string]*Request{}
+	for _, r := range c.Requests {
+		requests[r.Name] = r
+	}
+	login := requests["Login"]
+	if login.Body != "user={{user}}&pass+word=a%26b" || login.ContentType != "application/x-www-form-urlencoded" || login.Auth != nil {
+		t.Errorf("login = %q %q %+v", login.ContentType, login.Body, login.Auth)
+	}
+	search := requests["Search"]
+	if want := "{\n  \"query\": \"{ users { id } }\",\n  \"variables\": {\n    \"first\": 2\n  }\n}"; search.Body != want || search.ContentType != "application/json" {
+		t.Errorf("search body = %q %q, want %q", search.ContentType, search.Body, want)
+	}
+
+	wantWarnings := []string{
+		"Shop > Users > Get user: 2 saved example responses were skipped",
+		"Shop > Users > Create user: scripts are not supported",
+		"Shop > Login: oauth2 authorization_code grant is not supported",
+		"Shop > Upload: file body is not supported",
+		"Shop > Draft: skipped, url is required",
+		`Shop > Health: skipped, url "shop.example.com/health" must be absolute`,
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestIsPostmanCollection(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`{"info": {"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"}}`, true},
+		{`{"info": {"schema": "https://example.com"}}`, false},
+		{`{"openapi": "3.0.0"}`, false},
+		{`openapi: 3.0.0`, false},
+	}
+	for _, tt := range tests {
+		if got := isPostmanCollection([]byte(tt.data)); got != tt.want {
+			t.Errorf("isPostmanCollection(%s) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
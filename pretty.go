@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatHTML = "html"
+
+	// maxHighlightSize keeps huge responses responsive, they are only
+	// pretty-printed
+	maxHighlightSize = 1 << 20
+)
+
+// htmlVoidElements never have content or an end tag.
+var htmlVoidElements = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+
+// bodyFormat returns the format of a body with the given content type, or ""
+// when it is not pretty-printed. Bodies without a specific type are sniffed.
+func bodyFormat(contentType, body string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json"):
+		return FormatJSON
+	case strings.HasSuffix(mediaType, "/html") || mediaType == "application/xhtml+xml":
+		return FormatHTML
+	case strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml"):
+		return FormatXML
+	case mediaType != "" && mediaType != "text/plain" && mediaType != "application/octet-stream":
+		return ""
+	}
+
+	trimmed := strings.TrimSpace(body)
+	switch {
+	case json.Valid([]byte(trimmed)) && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")):
+		return FormatJSON
+	case strings.HasPrefix(trimmed, "<"):
+		if strings.HasPrefix(http.DetectContentType([]byte(trimmed)), "text/html") {
+			return FormatHTML
+		}
+		return FormatXML
+	}
+
+	return ""
+}
+
+// prettyBody indents a body of the given format.
+func prettyBody(body, format string) (string, error) {
+	switch format {
+	case FormatJSON:
+		var b bytes.Buffer
+		if err := json.Indent(&b, []byte(body), "", "  "); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	case FormatXML, FormatHTML:
+		return indentMarkup(body, format == FormatHTML)
+	}
+
+	return "", errors.New("unknown format")
+}
+
+// highlightBody colors s for the terminal, leaving it unchanged when there is
+// no lexer for format.
+func highlightBody(s, format string) string {
+	lexer := lexers.Get(format)
+	if lexer == nil || len(s) > maxHighlightSize {
+		return s
+	}
+
+	style := styles.Get("monokai")
+	if !lipgloss.HasDarkBackground() {
+		style = styles.Get("github")
+	}
+
+	iterator, err := lexer.Tokenise(nil, s)
+	if err != nil {
+		return s
+	}
+
+	var b strings.Builder
+	if err := formatters.TTY256.Format(&b, style, iterator); err != nil {
+		return s
+	}
+
+	return b.String()
+}
+
+// indentMarkup puts every element of an XML or HTML document on its own line,
+// keeping elements with only text on one line.
+func indentMarkup(s string, html bool) (string, error) {
+	d := xml.NewDecoder(strings.NewReader(s))
+	if html {
+		d.Strict = false
+		d.AutoClose = xml.HTMLAutoClose
+		d.Entity = xml.HTMLEntity
+	}
+
+	var tokens []xml.Token
+	for {
+		t, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		tokens = append(tokens, xml.CopyToken(t))
+	}
+
+	var b strings.Builder
+	depth := 0
+	line := func(s string) {
+		b.WriteString(strings.Repeat("  ", depth) + s + "\n")
+	}
+	isEnd := func(i int, name xml.Name) bool {
+		end, ok := tokens[i].(xml.EndElement)
+		return ok && end.Name == name
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch t := tokens[i].(type) {
+		case xml.StartElement:
+			tag := startTag(t)
+			void := html && slices.Contains(htmlVoidElements, strings.ToLower(t.Name.Local))
+
+			text := ""
+			next := i + 1
+			if next < len(tokens) {
+				if data, ok := tokens[next].(xml.CharData); ok {
+					text = strings.TrimSpace(string(data))
+					next++
+				}
+			}
+			switch {
+			case void:
+				line(tag)
+			case next < len(tokens) && isEnd(next, t.Name) && text == "" && !html:
+				line(strings.TrimSuffix(tag, ">") + "/>")
+				i = next
+			case next < len(tokens) && isEnd(next, t.Name) && !strings.Contains(text, "\n"):
+				line(tag + escapeMarkup(text) + "</" + markupName(t.Name) + ">")
+				i = next
+			default:
+				line(tag)
+				depth++
+			}
+		case xml.EndElement:
+			if html && slices.Contains(htmlVoidElements, strings.ToLower(t.Name.Local)) {
+				continue
+			}
+			depth = max(depth-1, 0)
+			line("</" + markupName(t.Name) + ">")
+		case xml.CharData:
+			for text := range strings.Lines(string(t)) {
+				if text = strings.TrimSpace(text); text != "" {
+					line(escapeMarkup(text))
+				}
+			}
+		case xml.Comment:
+			line("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			line("<?" + strings.TrimSpace(t.Target+" "+string(t.Inst)) + "?>")
+		case xml.Directive:
+			line("<!" + string(t) + ">")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func startTag(t xml.StartElement) string {
+	var b strings.Builder
+	b.WriteString("<" + markupName(t.Name))
+	for _, attr := range t.Attr {
+		b.WriteString(" " + markupName(attr.Name) + `="` + strings.ReplaceAll(escapeMarkup(attr.Value), `"`, "&quot;") + `"`)
+	}
+	b.WriteString(">")
+
+	return b.String()
+}
+
+// markupName returns the name as written, RawToken keeps the prefix in Space.
+func markupName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeMarkup(s string) string {
+	return markupEscaper.Replace(s)
+}
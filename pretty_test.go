@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBodyFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"application/json", "not json", FormatJSON},
+		{"application/problem+json; charset=utf-8", "{}", FormatJSON},
+		{"text/html; charset=utf-8", "<p>", FormatHTML},
+		{"application/xhtml+xml", "<html/>", FormatHTML},
+		{"application/xml", "<a/>", FormatXML},
+		{"application/atom+xml", "<feed/>", FormatXML},
+		{"text/csv", `{"a": 1}`, ""},
+		{"", `{"a": 1}`, FormatJSON},
+		{"text/plain", " [1, 2] ", FormatJSON},
+		{"application/octet-stream", `{"a": 1}`, FormatJSON},
+		{"", `"just a string"`, ""},
+		{"", "42", ""},
+		{"", "<!DOCTYPE html><html><body>hi</body></html>", FormatHTML},
+		{"", `<?xml version="1.0"?><a/>`, FormatXML},
+		{"", "plain text", ""},
+	}
+	for _, tt := range tests {
+		if got := bodyFormat(tt.contentType, tt.body); got != tt.want {
+			t.Errorf("bodyFormat(%q, %q) = %q, want %q", tt.contentType, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestPrettyBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "json", format: FormatJSON,
+			body: `{"a":[1,2],"b":{}}`,
+			want: "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}",
+		},
+		{
+			name: "invalid json", format: FormatJSON,
+			body: `{"a":`, wantErr: true,
+		},
+		{
+			name: "xml", format: FormatXML,
+			body: `<?xml version="1.0"?><a:feed xmlns:a="urn:a"><!-- list --><item id="1">x &amp; y</item><empty></empty><b><c/></b></a:feed>`,
+			want: `<?xml version="1.0"?>
+<a:feed xmlns:a="urn:a">
+  <!-- list -->
+  <item id="1">x &amp; y</item>
+  <empty/>
+  <b>
+    <c/>
+  </b>
+</a:feed>`,
+		},
+		{
+			name: "invalid xml", format: FormatXML,
+			body: `<a><b`, wantErr: true,
+		},
+		{
+			name: "html", format: FormatHTML,
+			body: `<!DOCTYPE html><html><head><meta charset="utf-8"><title>Hi</title></head><body><p>one<br>two</p><div></div></body></html>`,
+			want: `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Hi</title>
+  </head>
+  <body>
+    <p>
+      one
+      <br>
+      two
+    </p>
+    <div></div>
+  </body>
+</html>`,
+		},
+		{
+			name: "unknown format", format: "yaml",
+			body: "a: 1", wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prettyBody(tt.body, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prettyBody() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("prettyBody() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightBody(t *testing.T) {
+	small := `{"a": 1}`
+	if got := highlightBody(small, FormatJSON); got == small || !strings.Contains(got, "\x1b[") {
+		t.Errorf("highlightBody() = %q, want it colored", got)
+	}
+	if got := highlightBody(small, "nope"); got != small {
+		t.Errorf("highlightBody() without a lexer = %q, want it unchanged", got)
+	}
+
+	large := `{"a": "` + strings.Repeat("x", maxHighlightSize) + `"}`
+	if got := highlightBody(large, FormatJSON); got != large {
+		t.Errorf("highlightBody() of a body over %d bytes changed it", maxHighlightSize)
+	}
+}
+
+func TestRenderResponseBody(t *testing.T) {
+	m := newTestModel(t)
+	body := `{"name":"Ada"}`
+	cmd := m.showResponse(responseMsg{requestID: m.requestID, statusCode: 200, responseHeaders: "Content-Type: application/json", responseBody: body})
+
+	// The body is shown as received while it is formatted off the ui loop
+	if m.tabContent[TabResponseBody] != body {
+		t.Errorf("body before formatting = %q, want it as received", m.tabContent[TabResponseBody])
+	}
+	highlighted := runHighlight(t, cmd)
+	if highlighted.source != body || highlighted.format != FormatJSON || !strings.Contains(highlighted.body, "\x1b[") {
+		t.Fatalf("highlightMsg = %+v", highlighted)
+	}
+
+	// A result for a body no longer shown is ignored
+	updated, _ := m.Update(highlightMsg{highlightID: m.highlightID - 1, body: "stale"})
+	m = updated.(model)
+	if m.tabContent[TabResponseBody] != body {
+		t.Errorf("body = %q after a stale highlightMsg", m.tabContent[TabResponseBody])
+	}
+
+	updated, _ = m.Update(highlighted)
+	m = updated.(model)
+	if m.tabContent[TabResponseBody] != highlighted.body || !strings.Contains(m.responseView.View(), "Ada") {
+		t.Errorf("body = %q, want the highlighted one", m.tabContent[TabResponseBody])
+	}
+
+	toggleRaw := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p"), Alt: true}
+	updated, _ = m.Update(toggleRaw)
+	m = updated.(model)
+	if !m.rawResponse || m.tabContent[TabResponseBody] != body || m.statusMessage != "Showing the raw response body" {
+		t.Errorf("raw body = %q, %q", m.tabContent[TabResponseBody], m.statusMessage)
+	}
+
+	// Toggling back reuses the formatted body
+	updated, cmd = m.Update(toggleRaw)
+	m = updated.(model)
+	if m.rawResponse || m.tabContent[TabResponseBody] != highlighted.body || cmd != nil {
+		t.Errorf("formatted body = %q, command %v, want the highlighted one without formatting again", m.tabContent[TabResponseBody], cmd)
+	}
+}
+
+// runHighlight runs cmd and returns the highlightMsg of the commands it
+// batches.
+func runHighlight(t *testing.T, cmd tea.Cmd) highlightMsg {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("no command to format the body")
+	}
+	switch msg := cmd().(type) {
+	case highlightMsg:
+		return msg
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			if cmd == nil {
+				continue
+			}
+			if msg, ok := cmd().(highlightMsg); ok {
+				return msg
+			}
+		}
+	}
+	t.Fatal("no highlightMsg")
+
+	return highlightMsg{}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type PromptAction int
+
+const (
+	PromptNone PromptAction = iota
+	PromptImport
+	PromptExport
+	PromptEnvironment
+	PromptUnlockVault
+	PromptHistoryFilter
+	PromptBodyFilter
+	PromptSearch
+)
+
+func (m *model) openPrompt(action PromptAction, prompt, placeholder string) tea.Cmd {
+	m.promptAction = action
+	m.prompt.Prompt = prompt
+	m.prompt.Placeholder = placeholder
+	m.prompt.SetValue("")
+	m.prompt.SetSuggestions(nil)
+	m.prompt.EchoMode = textinput.EchoNormal
+	m.err = nil
+
+	return m.prompt.Focus()
+}
+
+func (m *model) openVaultPrompt() tea.Cmd {
+	placeholder := "passphrase of the secret vault"
+	if path, err := vaultPath(); err == nil {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			placeholder = "choose a passphrase for the new secret vault"
+		}
+	}
+
+	cmd := m.openPrompt(PromptUnlockVault, "Vault passphrase: ", placeholder)
+	m.prompt.EchoMode = textinput.EchoPassword
+
+	return cmd
+}
+
+func (m *model) closePrompt() {
+	m.promptAction = PromptNone
+	m.prompt.Blur()
+}
+
+func (m model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		var cmd tea.Cmd
+		switch m.promptAction {
+		case PromptBodyFilter:
+			cmd = m.setBodyFilter(m.previousBodyFilter)
+		case PromptSearch:
+			m.search = m.previousSearch
+			m.responseView.SetYOffset(m.searchLine)
+		}
+		m.closePrompt()
+		return m, cmd
+	case "enter":
+		action, value := m.promptAction, m.prompt.Value()
+		m.closePrompt()
+		if err := m.submitPrompt(action, value); err != nil {
+			return m, func() tea.Msg {
+				return errMsg{err: err}
+			}
+		}
+		return m, nil
+	case "alt+c", "alt+r":
+		if m.promptAction != PromptSearch {
+			break
+		}
+		if msg.String() == "alt+c" {
+			m.searchCase = !m.searchCase
+		} else {
+			m.searchRegex = !m.searchRegex
+		}
+		m.searchPrompt()
+		m.setSearch(m.prompt.Value(), m.searchLine)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+
+	// The body filter and search are applied while typing
+	switch m.promptAction {
+	case PromptBodyFilter:
+		cmd = tea.Batch(cmd, m.setBodyFilter(m.prompt.Value()))
+	case PromptSearch:
+		m.setSearch(m.prompt.Value(), m.searchLine)
+	}
+
+	return m, cmd
+}
+
+func (m *model) submitPrompt(action PromptAction, value string) error {
+	// These prompts also act on an empty value
+	switch action {
+	case PromptEnvironment:
+		return m.switchEnvironment(strings.TrimSpace(value))
+	case PromptUnlockVault:
+		return m.unlockVault(value)
+	case PromptHistoryFilter:
+		m.historyFilter = strings.TrimSpace(value)
+		m.selectHistory(0)
+		return nil
+	case PromptBodyFilter, PromptSearch:
+		// Already applied while typing
+		return nil
+	}
+
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	switch action {
+	case PromptImport:
+		collection, warnings, err := importCollectionFile(value)
+		if err != nil {
+			return err
+		}
+
+		m.activeCollection = collection
+		m.collectionFile = ""
+		m.requestPath = nil
+		m.renderAuth()
+		if err := m.persistCollection(); err != nil {
+			return err
+		}
+		if err := m.renderCollection(); err != nil {
+			return err
+		}
+
+		m.statusMessage = fmt.Sprintf("Imported %d requests into %q", len(collection.allRequests()), collection.Name)
+		m.statusMessage += m.setWarnings("Imported "+value, warnings)
+	case PromptExport:
+		format, path := parseExportTarget(value)
+		collection, err := m.maskCollection(m.activeCollection)
+		if err != nil {
+			return err
+		}
+		if err := exportCollectionFile(collection, format, path); err != nil {
+			return err
+		}
+
+		m.statusMessage = fmt.Sprintf("Exported %q as %s collection to %s", m.activeCollection.Name, format, path)
+	}
+
+	return nil
+}
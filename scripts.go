@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	preRequestSection   = "[pre-request]"
+	postResponseSection = "[post-response]"
+
+	// scriptTimeout stops scripts that never finish
+	scriptTimeout = 5 * time.Second
+)
+
+// scriptPrelude defines the helpers that are easier written in JavaScript.
+const scriptPrelude = `function assert(condition, message) {
+	if (!condition) {
+		throw new Error(message || "assertion failed")
+	}
+}`
+
+// Scripts are the JavaScript run before a request is sent and after its
+// response arrives.
+type Scripts struct {
+	PreRequest   string `json:"preRequest,omitempty"`
+	PostResponse string `json:"postResponse,omitempty"`
+}
+
+func (s *Scripts) Validate() error {
+	if _, err := goja.Compile("pre-request", s.PreRequest, false); err != nil {
+		return err
+	}
+	if _, err := goja.Compile("post-response", s.PostResponse, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Scripts) isEmpty() bool {
+	return s.PreRequest == "" && s.PostResponse == ""
+}
+
+// formatScripts renders the scripts for the Scripts editor.
+func formatScripts(s *Scripts) string {
+	if s == nil {
+		s = &Scripts{}
+	}
+
+	var b strings.Builder
+	b.WriteString("# JavaScript run before the request is sent and after its response arrives,\n")
+	b.WriteString("# stored with the request in the collection. Scripts can change request.url,\n")
+	b.WriteString("# method, headers and body, read response.status, headers, body, json() and\n")
+	b.WriteString("# time, and call variables.get/set, test(name, fn), assert and console.log.\n")
+	b.WriteString(preRequestSection + "\n")
+	if s.PreRequest != "" {
+		b.WriteString(s.PreRequest + "\n")
+	}
+	b.WriteString("\n" + postResponseSection + "\n")
+	b.WriteString(s.PostResponse)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// parseScripts reads the Scripts editor, it returns nil when both scripts are
+// empty.
+func parseScripts(text string) (*Scripts, error) {
+	var pre, post []string
+	var section *[]string
+	for i, line := range strings.Split(text, "\n") {
+		switch strings.TrimSpace(line) {
+		case preRequestSection:
+			section = &pre
+			continue
+		case postResponseSection:
+			section = &post
+			continue
+		}
+
+		if section == nil {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				return nil, fmt.Errorf("line %d: expected %s or %s", i+1, preRequestSection, postResponseSection)
+			}
+			continue
+		}
+		*section = append(*section, line)
+	}
+
+	s := &Scripts{
+		PreRequest:   strings.TrimSpace(strings.Join(pre, "\n")),
+		PostResponse: strings.TrimSpace(strings.Join(post, "\n")),
+	}
+	if s.isEmpty() {
+		return nil, nil
+	}
+
+	return s, s.Validate()
+}
+
+// scriptResult is what a script did besides changing the request.
+type scriptResult struct {
+	variables map[string]string
+	tests     []testResult
+	logs      []string
+}
+
+// scriptRuntime runs one script, lookup resolves the variables it reads.
+type scriptRuntime struct {
+	vm     *goja.Runtime
+	result *scriptResult
+	lookup func(string) (string, bool)
+}
+
+func newScriptRuntime(lookup func(string) (string, bool)) (*scriptRuntime, error) {
+	r := &scriptRuntime{
+		vm:     goja.New(),
+		result: &scriptResult{variables: map[string]string{}},
+		lookup: lookup,
+	}
+
+	variables := r.vm.NewObject()
+	if err := variables.Set("get", r.getVariable); err != nil {
+		return nil, err
+	}
+	if err := variables.Set("set", r.setVariable); err != nil {
+		return nil, err
+	}
+	console := r.vm.NewObject()
+	if err := console.Set("log", r.log); err != nil {
+		return nil, err
+	}
+
+	for name, value := range map[string]any{"variables": variables, "console": console, "test": r.test} {
+		if err := r.vm.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.vm.RunString(scriptPrelude); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *scriptRuntime) getVariable(call goja.FunctionCall) goja.Value {
+	name := call.Argument(0).String()
+	if value, ok := r.result.variables[name]; ok {
+		return r.vm.ToValue(value)
+	}
+	if value, ok := r.lookup(name); ok {
+		return r.vm.ToValue(value)
+	}
+
+	return goja.Undefined()
+}
+
+func (r *scriptRuntime) setVariable(call goja.FunctionCall) goja.Value {
+	name := call.Argument(0).String()
+	if !variableNamePattern.MatchString(name) {
+		panic(r.vm.NewTypeError("invalid variable name %q", name))
+	}
+	r.result.variables[name] = r.string(call.Argument(1))
+
+	return goja.Undefined()
+}
+
+func (r *scriptRuntime) log(call goja.FunctionCall) goja.Value {
+	parts := make([]string, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		parts[i] = r.string(arg)
+	}
+	r.result.logs = append(r.result.logs, strings.Join(parts, " "))
+
+	return goja.Undefined()
+}
+
+// test runs the function passed as its second argument, which passes unless
+// it throws.
+func (r *scriptRuntime) test(call goja.FunctionCall) goja.Value {
+	name := call.Argument(0).String()
+	fn, ok := goja.AssertFunction(call.Argument(1))
+	if !ok {
+		panic(r.vm.NewTypeError("test needs a name and a function"))
+	}
+
+	result := testResult{name: name, passed: true}
+	if _, err := fn(goja.Undefined()); err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			panic(err)
+		}
+		result.passed, result.message = false, scriptError(err)
+	}
+	r.result.tests = append(r.result.tests, result)
+
+	return goja.Undefined()
+}
+
+// string converts a script value to text, objects as json.
+func (r *scriptRuntime) string(v goja.Value) string {
+	obj, ok := v.(*goja.Object)
+	if !ok || obj.ClassName() == "Function" {
+		return v.String()
+	}
+
+	stringify, ok := goja.AssertFunction(r.vm.Get("JSON").ToObject(r.vm).Get("stringify"))
+	if !ok {
+		return v.String()
+	}
+	s, err := stringify(goja.Undefined(), v)
+	if err != nil || goja.IsUndefined(s) {
+		return v.String()
+	}
+
+	return s.String()
+}
+
+// run runs script until it finishes, times out or ctx is cancelled.
+func (r *scriptRuntime) run(ctx context.Context, name, script string) error {
+	program, err := goja.Compile(name, script, false)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, scriptTimeout, fmt.Errorf("timed out after %s", scriptTimeout))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		r.vm.Interrupt(context.Cause(ctx).Error())
+	})
+	defer stop()
+
+	if _, err := r.vm.RunProgram(program); err != nil {
+		return fmt.Errorf("%s script: %w", name, err)
+	}
+
+	return nil
+}
+
+// scriptError returns the message of an error thrown by a script.
+func scriptError(err error) string {
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return exception.Value().String()
+	}
+
+	return err.Error()
+}
+
+// runPreRequestScript runs script on the request in the editors, which it can
+// change before the variables are substituted.
+func runPreRequestScript(ctx context.Context, script string, draft *requestDraft, lookup func(string) (string, bool)) (*scriptResult, error) {
+	r, err := newScriptRuntime(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	request := r.vm.NewObject()
+	headers := map[string]any{}
+	for key, value := range draft.headers {
+		headers[key] = value
+	}
+	for key, value := range map[string]any{"url": draft.url, "method": draft.method, "headers": headers, "body": draft.body} {
+		if err := request.Set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.vm.Set("request", request); err != nil {
+		return nil, err
+	}
+
+	if err := r.run(ctx, "pre-request", script); err != nil {
+		return r.result, err
+	}
+
+	draft.url = r.optionalString(request.Get("url"))
+	draft.method = r.optionalString(request.Get("method"))
+	draft.body = r.optionalString(request.Get("body"))
+	changed, ok := request.Get("headers").Export().(map[string]any)
+	if !ok {
+		return r.result, errors.New("pre-request script: request.headers must be an object")
+	}
+	draft.headers = map[string]string{}
+	for key, value := range changed {
+		draft.headers[key] = r.string(r.vm.ToValue(value))
+	}
+
+	return r.result, nil
+}
+
+// runPostResponseScript runs script with the request that was sent and its
+// response.
+func runPostResponseScript(ctx context.Context, script string, sent *HistoryEntry, resp responseMsg, lookup func(string) (string, bool)) (*scriptResult, error) {
+	r, err := newScriptRuntime(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]any{}
+	if sent != nil {
+		headers := map[string]any{}
+		for key, value := range sent.Headers {
+			headers[key] = value
+		}
+		request = map[string]any{"url": sent.URL, "method": sent.Method, "headers": headers, "body": sent.Body}
+	}
+
+	response := map[string]any{
+		"status":  resp.statusCode,
+		"headers": responseHeaderObject(resp.responseHeaders),
+		"body":    resp.responseBody,
+		"time":    resp.responseTime,
+		"header": func(name string) any {
+			if value, found := lookupHeaderLine(resp.responseHeaders, name); found {
+				return value
+			}
+			return nil
+		},
+	}
+
+	for name, value := range map[string]any{"request": request, "response": response} {
+		if err := r.vm.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.vm.RunString(`response.json = function () { return JSON.parse(response.body) }`); err != nil {
+		return nil, err
+	}
+
+	return r.result, r.run(ctx, "post-response", script)
+}
+
+// optionalString converts a script value to text, with undefined and null
+// as empty text.
+func (r *scriptRuntime) optionalString(v goja.Value) string {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return ""
+	}
+
+	return r.string(v)
+}
+
+// responseHeaderObject turns "Name: value" lines into an object, joining the
+// values of repeated headers.
+func responseHeaderObject(headers string) map[string]any {
+	values := map[string][]string{}
+	for line := range strings.SplitSeq(headers, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		values[key] = append(values[key], strings.TrimSpace(value))
+	}
+
+	object := map[string]any{}
+	for key, values := range values {
+		object[key] = strings.Join(values, ", ")
+	}
+
+	return object
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    *Scripts
+		wantErr string
+	}{
+		{
+			name: "empty",
+			text: formatScripts(nil),
+		},
+		{
+			name: "both sections",
+			text: "# comment\n[pre-request]\nrequest.url = 'x'\n\n[post-response]\n  test('ok', () => {})\n",
+			want: &Scripts{PreRequest: "request.url = 'x'", PostResponse: "test('ok', () => {})"},
+		},
+		{
+			name: "post-response only",
+			text: "[post-response]\nconsole.log(1)",
+			want: &Scripts{PostResponse: "console.log(1)"},
+		},
+		{
+			name: "round trip",
+			text: formatScripts(&Scripts{PreRequest: "let a = 1\nlet b = 2", PostResponse: "a + b"}),
+			want: &Scripts{PreRequest: "let a = 1\nlet b = 2", PostResponse: "a + b"},
+		},
+		{
+			name:    "code outside a section",
+			text:    "request.url = 'x'\n[pre-request]",
+			wantErr: "line 1",
+		},
+		{
+			name:    "syntax error",
+			text:    "[pre-request]\nif (",
+			wantErr: "pre-request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseScripts(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseScripts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseScripts() error = %v", err)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("parseScripts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunPreRequestScript(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "host" {
+			return "api.example.com", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name      string
+		script    string
+		want      requestDraft
+		variables map[string]string
+		wantErr   string
+	}{
+		{
+			name:   "unchanged",
+			script: "",
+			want: requestDraft{
+				url: "https://example.com", method: "GET",
+				headers: map[string]string{"Accept": "*/*"},
+			},
+		},
+		{
+			name: "url, headers and body",
+			script: `request.url = "https://" + variables.get("host") + "/users"
+request.method = "POST"
+request.headers["X-Id"] = 42
+delete request.headers.Accept
+request.body = JSON.stringify({name: "a"})`,
+			want: requestDraft{
+				url: "https://api.example.com/users", method: "POST",
+				headers: map[string]string{"X-Id": "42"},
+				body:    `{"name":"a"}`,
+			},
+		},
+		{
+			name:   "null body",
+			script: "request.body = null",
+			want: requestDraft{
+				url: "https://example.com", method: "GET",
+				headers: map[string]string{"Accept": "*/*"},
+			},
+		},
+		{
+			name:   "set variables",
+			script: `variables.set("token", "abc"); variables.set("user", {id: 1}); request.url = variables.get("token")`,
+			want: requestDraft{
+				url: "abc", method: "GET",
+				headers: map[string]string{"Accept": "*/*"},
+			},
+			variables: map[string]string{"token": "abc", "user": `{"id":1}`},
+		},
+		{
+			name:    "invalid variable name",
+			script:  `variables.set("a b", "c")`,
+			wantErr: "invalid variable name",
+		},
+		{
+			name:    "headers replaced",
+			script:  "request.headers = 1",
+			wantErr: "request.headers must be an object",
+		},
+		{
+			name:    "thrown error",
+			script:  `assert(false, "no token")`,
+			wantErr: "no token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft := requestDraft{url: "https://example.com", method: "GET", headers: map[string]string{"Accept": "*/*"}}
+			result, err := runPreRequestScript(context.Background(), tt.script, &draft, lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("runPreRequestScript() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runPreRequestScript() error = %v", err)
+			}
+			if draft.url != tt.want.url || draft.method != tt.want.method || draft.body != tt.want.body || !maps.Equal(draft.headers, tt.want.headers) {
+				t.Errorf("draft = %+v, want %+v", draft, tt.want)
+			}
+			if len(result.variables) != 0 || len(tt.variables) != 0 {
+				if !maps.Equal(result.variables, tt.variables) {
+					t.Errorf("variables = %v, want %v", result.variables, tt.variables)
+				}
+			}
+		})
+	}
+}
+
+func TestRunPreRequestScriptCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	draft := requestDraft{}
+	_, err := runPreRequestScript(ctx, "while (true) {}", &draft, func(string) (string, bool) { return "", false })
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("runPreRequestScript() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRunPostResponseScript(t *testing.T) {
+	resp := responseMsg{statusCode: 201, responseBody: `{"id": 7}`, responseHeaders: "Content-Type: application/json\nSet-Cookie: a=1\nSet-Cookie: b=2"}
+	script := `test("created", () => assert(response.status === 201))
+test("json", () => assert(response.json().id === 8, "wrong id"))
+variables.set("id", response.json().id)
+console.log(response.header("content-type"), response.headers["Set-Cookie"])`
+
+	result, err := runPostResponseScript(context.Background(), script, nil, resp, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("runPostResponseScript() error = %v", err)
+	}
+
+	want := []testResult{{name: "created", passed: true}, {name: "json", message: "Error: wrong id"}}
+	if len(result.tests) != len(want) {
+		t.Fatalf("tests = %+v, want %+v", result.tests, want)
+	}
+	for i := range want {
+		if result.tests[i] != want[i] {
+			t.Errorf("tests[%d] = %+v, want %+v", i, result.tests[i], want[i])
+		}
+	}
+	if result.variables["id"] != "7" {
+		t.Errorf("variables = %v, want id=7", result.variables)
+	}
+	if len(result.logs) != 1 || result.logs[0] != "application/json a=1, b=2" {
+		t.Errorf("logs = %q", result.logs)
+	}
+}
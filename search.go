@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	matchStyle        = lipgloss.NewStyle().Background(lipgloss.AdaptiveColor{Light: "#F5E0A3", Dark: "#5C5326"})
+	currentMatchStyle = lipgloss.NewStyle().Background(lipgloss.Color("#FFC83D")).Foreground(lipgloss.Color("#000000"))
+)
+
+// searchMatch is a match on a line of the response viewport, start and end
+// are the cells it takes up on that line.
+type searchMatch struct {
+	line, start, end int
+}
+
+// compileSearch returns the expression matching query, which is literal text
+// unless regex is set.
+func compileSearch(query string, regex, matchCase bool) (*regexp.Regexp, error) {
+	if !regex {
+		query = regexp.QuoteMeta(query)
+	}
+	if _, err := regexp.Compile(query); err != nil {
+		return nil, fmt.Errorf("invalid search: %w", err)
+	}
+	if !matchCase {
+		query = "(?i)" + query
+	}
+
+	return regexp.Compile(query)
+}
+
+// findMatches looks up re in the text of content, without its colors.
+func findMatches(content string, re *regexp.Regexp) []searchMatch {
+	var matches []searchMatch
+	for i, line := range strings.Split(ansi.Strip(content), "\n") {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			// Empty matches can't be highlighted
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, searchMatch{
+				line:  i,
+				start: ansi.StringWidth(line[:loc[0]]),
+				end:   ansi.StringWidth(line[:loc[1]]),
+			})
+		}
+	}
+
+	return matches
+}
+
+// highlightMatches marks the matches in content, keeping the colors around
+// them.
+func highlightMatches(content string, matches []searchMatch, current int) string {
+	lines := strings.Split(content, "\n")
+
+	// From the last match so the cells of the earlier ones stay the same
+	for i := len(matches) - 1; i >= 0; i-- {
+		match := matches[i]
+		if match.line >= len(lines) {
+			continue
+		}
+
+		style := matchStyle
+		if i == current {
+			style = currentMatchStyle
+		}
+		line := lines[match.line]
+		text := ansi.Strip(ansi.Cut(line, match.start, match.end))
+		lines[match.line] = ansi.Truncate(line, match.start, "") + style.Render(text) + ansi.TruncateLeft(line, match.end, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// firstMatchFrom returns the first match on or after line, wrapping around to
+// the first match.
+func firstMatchFrom(matches []searchMatch, line int) int {
+	for i, match := range matches {
+		if match.line >= line {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// searchStatus returns the match counter of a search.
+func searchStatus(matches []searchMatch, current int, err error) string {
+	switch {
+	case err != nil:
+		return errorStyle.Render(err.Error())
+	case len(matches) == 0:
+		return "no matches"
+	}
+
+	return fmt.Sprintf("%d/%d %s", current+1, len(matches), plural(len(matches), "match", "matches"))
+}
+
+func (m *model) isSearchTab() bool {
+	return m.activeTab == TabResponseBody || m.activeTab == TabResponseHeaders
+}
+
+// searchCache keeps the matches of the last search, so they are only looked up
+// again when the query, its modes or the content change.
+type searchCache struct {
+	query            string
+	regex, matchCase bool
+	content          string
+	matches          []searchMatch
+	err              error
+
+	current     int
+	highlighted string
+}
+
+// searchMatches returns the matches of the search in the active tab.
+func (m *model) searchMatches() ([]searchMatch, error) {
+	if m.search == "" || !m.isSearchTab() {
+		return nil, nil
+	}
+
+	content := m.tabContent[m.activeTab]
+	c := m.searchCache
+	if c == nil {
+		c = &searchCache{}
+	}
+	if c.query == m.search && c.regex == m.searchRegex && c.matchCase == m.searchCase && c.content == content {
+		return c.matches, c.err
+	}
+
+	*c = searchCache{query: m.search, regex: m.searchRegex, matchCase: m.searchCase, content: content, current: -1}
+	re, err := compileSearch(m.search, m.searchRegex, m.searchCase)
+	if err != nil {
+		c.err = err
+	} else {
+		c.matches = findMatches(content, re)
+	}
+
+	return c.matches, c.err
+}
+
+// highlightedContent returns the content of the active tab with the matches
+// of the search marked.
+func (m *model) highlightedContent(matches []searchMatch, current int) string {
+	c := m.searchCache
+	if c == nil {
+		return highlightMatches(m.tabContent[m.activeTab], matches, current)
+	}
+	if c.current != current {
+		c.current = current
+		c.highlighted = highlightMatches(c.content, matches, current)
+	}
+
+	return c.highlighted
+}
+
+// currentMatch returns the index of the current match of n matches, which
+// can change with the content of the tab.
+func (m *model) currentMatch(n int) int {
+	if n == 0 {
+		return 0
+	}
+
+	return (m.searchIndex%n + n) % n
+}
+
+// setSearch looks up query and scrolls to its first match on or after line.
+func (m *model) setSearch(query string, line int) {
+	m.search = query
+	matches, _ := m.searchMatches()
+	if len(matches) == 0 {
+		return
+	}
+
+	m.showMatch(matches, firstMatchFrom(matches, line))
+}
+
+// nextMatch moves delta matches on, wrapping around at either end.
+func (m *model) nextMatch(delta int) {
+	matches, _ := m.searchMatches()
+	if len(matches) == 0 {
+		return
+	}
+
+	m.showMatch(matches, m.currentMatch(len(matches))+delta)
+}
+
+func (m *model) showMatch(matches []searchMatch, i int) {
+	m.searchIndex = i
+	m.searchIndex = m.currentMatch(len(matches))
+	match := matches[m.searchIndex]
+	m.keepInView(match.line)
+
+	width := max(m.responseView.Width-m.responseView.Style.GetHorizontalFrameSize(), 1)
+	if match.end <= width {
+		m.responseView.SetXOffset(0)
+	} else {
+		m.responseView.SetXOffset(match.end - width)
+	}
+}
+
+// searchPrompt shows the search modes in the label of the search prompt.
+func (m *model) searchPrompt() {
+	var modes []string
+	if m.searchCase {
+		modes = append(modes, "match case")
+	}
+	if m.searchRegex {
+		modes = append(modes, "regex")
+	}
+
+	m.prompt.Prompt = "Search: "
+	if len(modes) > 0 {
+		m.prompt.Prompt = "Search (" + strings.Join(modes, ", ") + "): "
+	}
+}
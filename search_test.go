@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		query     string
+		regex     bool
+		matchCase bool
+		want      []searchMatch
+	}{
+		{"literal", "a.b a.b\naxb", "a.b", false, false, []searchMatch{{0, 0, 3}, {0, 4, 7}}},
+		{"regex", "a.b a.b\naxb", "a.b", true, false, []searchMatch{{0, 0, 3}, {0, 4, 7}, {1, 0, 3}}},
+		{"ignore case", "Token token", "TOKEN", false, false, []searchMatch{{0, 0, 5}, {0, 6, 11}}},
+		{"match case", "Token token", "token", false, true, []searchMatch{{0, 6, 11}}},
+		{"colors", "\x1b[31m\"id\"\x1b[0m: 1", "id", false, false, []searchMatch{{0, 1, 3}}},
+		{"wide runes", "日本 id", "id", false, false, []searchMatch{{0, 5, 7}}},
+		{"empty matches", "abc", "x*", true, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileSearch(tt.query, tt.regex, tt.matchCase)
+			if err != nil {
+				t.Fatalf("compileSearch() error = %v", err)
+			}
+			if got := findMatches(tt.content, re); !slices.Equal(got, tt.want) {
+				t.Errorf("findMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchMatchesCache(t *testing.T) {
+	m := initialModel()
+	m.activeTab = TabResponseBody
+	m.tabContent[TabResponseBody] = "one two one"
+	m.search = "one"
+
+	matches, err := m.searchMatches()
+	if err != nil || len(matches) != 2 {
+		t.Fatalf("searchMatches() = %v, %v, want 2 matches", matches, err)
+	}
+	if again, _ := m.searchMatches(); &again[0] != &matches[0] {
+		t.Errorf("searchMatches() looked up the matches again for the same search")
+	}
+
+	m.tabContent[TabResponseBody] = "one"
+	if matches, _ := m.searchMatches(); len(matches) != 1 {
+		t.Errorf("searchMatches() = %v after the content changed, want 1 match", matches)
+	}
+	m.searchCase = true
+	m.search = "ONE"
+	if matches, _ := m.searchMatches(); len(matches) != 0 {
+		t.Errorf("searchMatches() = %v after match case was set, want none", matches)
+	}
+	m.searchRegex = true
+	m.search = "("
+	if _, err := m.searchMatches(); err == nil {
+		t.Errorf("searchMatches() succeeded for an invalid regex")
+	}
+
+	m.search = "o"
+	m.searchRegex, m.searchCase = false, false
+	matches, _ = m.searchMatches()
+	if got, want := m.highlightedContent(matches, 0), highlightMatches("one", matches, 0); got != want {
+		t.Errorf("highlightedContent() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRedirects = 10
+)
+
+// Settings configure the http client. Unset fields fall back to the global
+// settings, and from there to the defaults.
+type Settings struct {
+	Timeout         string `json:"timeout,omitempty"`
+	FollowRedirects *bool  `json:"followRedirects,omitempty"`
+	MaxRedirects    *int   `json:"maxRedirects,omitempty"`
+	Proxy           string `json:"proxy,omitempty"`
+	Insecure        *bool  `json:"insecure,omitempty"`
+	CACert          string `json:"caCert,omitempty"`
+	ClientCert      string `json:"clientCert,omitempty"`
+	// ClientKey defaults to ClientCert for PEM files holding both
+	ClientKey string `json:"clientKey,omitempty"`
+	// PersistCookies keeps the cookie jar between runs, it is a global
+	// setting only
+	PersistCookies *bool `json:"persistCookies,omitempty"`
+}
+
+// settingKeys are the names used in the settings editor, in display order.
+var settingKeys = []string{"timeout", "followRedirects", "maxRedirects", "proxy", "insecure", "caCert", "clientCert", "clientKey", "persistCookies"}
+
+func defaultSettings() Settings {
+	follow, maxRedirects, insecure := true, defaultMaxRedirects, false
+	return Settings{
+		Timeout:         defaultTimeout.String(),
+		FollowRedirects: &follow,
+		MaxRedirects:    &maxRedirects,
+		Insecure:        &insecure,
+	}
+}
+
+func settingsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "settings.json"), nil
+}
+
+// loadSettings reads the global settings, which are empty until first saved.
+func loadSettings(path string) (Settings, error) {
+	var s Settings
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return s, s.Validate()
+}
+
+func saveSettings(path string, s Settings) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data, 0o644)
+}
+
+func (s *Settings) Validate() error {
+	if s.Timeout != "" {
+		if _, err := time.ParseDuration(s.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if s.MaxRedirects != nil && *s.MaxRedirects < 0 {
+		return errors.New("maxRedirects must not be negative")
+	}
+	if s.Proxy != "" {
+		if u, err := url.Parse(s.Proxy); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid proxy url %q", s.Proxy)
+		}
+	}
+
+	return nil
+}
+
+// merge returns s with the fields set in override replaced.
+func (s Settings) merge(override *Settings) Settings {
+	if override == nil {
+		return s
+	}
+
+	if override.Timeout != "" {
+		s.Timeout = override.Timeout
+	}
+	if override.FollowRedirects != nil {
+		s.FollowRedirects = override.FollowRedirects
+	}
+	if override.MaxRedirects != nil {
+		s.MaxRedirects = override.MaxRedirects
+	}
+	if override.Proxy != "" {
+		s.Proxy = override.Proxy
+	}
+	if override.Insecure != nil {
+		s.Insecure = override.Insecure
+	}
+	if override.CACert != "" {
+		s.CACert = override.CACert
+	}
+	if override.ClientCert != "" {
+		s.ClientCert = override.ClientCert
+	}
+	if override.ClientKey != "" {
+		s.ClientKey = override.ClientKey
+	}
+	if override.PersistCookies != nil {
+		s.PersistCookies = override.PersistCookies
+	}
+
+	return s
+}
+
+func (s *Settings) isEmpty() bool {
+	return *s == Settings{}
+}
+
+func (s *Settings) persistCookies() bool {
+	return s.PersistCookies != nil && *s.PersistCookies
+}
+
+func (s *Settings) get(key string) string {
+	switch key {
+	case "timeout":
+		return s.Timeout
+	case "followRedirects":
+		return formatOptional(s.FollowRedirects)
+	case "maxRedirects":
+		return formatOptional(s.MaxRedirects)
+	case "proxy":
+		return s.Proxy
+	case "insecure":
+		return formatOptional(s.Insecure)
+	case "caCert":
+		return s.CACert
+	case "clientCert":
+		return s.ClientCert
+	case "clientKey":
+		return s.ClientKey
+	case "persistCookies":
+		return formatOptional(s.PersistCookies)
+	}
+
+	return ""
+}
+
+func (s *Settings) set(key, value string) error {
+	var err error
+	switch key {
+	case "timeout":
+		s.Timeout = value
+	case "followRedirects":
+		s.FollowRedirects, err = parseOptional(value, strconv.ParseBool)
+	case "maxRedirects":
+		s.MaxRedirects, err = parseOptional(value, strconv.Atoi)
+	case "proxy":
+		s.Proxy = value
+	case "insecure":
+		s.Insecure, err = parseOptional(value, strconv.ParseBool)
+	case "caCert":
+		s.CACert = value
+	case "clientCert":
+		s.ClientCert = value
+	case "clientKey":
+		s.ClientKey = value
+	case "persistCookies":
+		s.PersistCookies, err = parseOptional(value, strconv.ParseBool)
+	default:
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func formatOptional[T any](v *T) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(*v)
+}
+
+func parseOptional[T any](value string, parse func(string) (T, error)) (*T, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	v, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// formatSettings renders the settings editor, with a [global] section for all
+// requests and a [request] section for the overrides of the current request.
+func formatSettings(global Settings, request *Settings) string {
+	if request == nil {
+		request = &Settings{}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Applied to every request, empty values use the defaults\n[global]\n")
+	for _, key := range settingKeys {
+		fmt.Fprintf(&b, "%s=%s\n", key, global.get(key))
+	}
+	b.WriteString("\n# Overrides for the current request, stored with it in the collection\n[request]\n")
+	for _, key := range settingKeys {
+		if key != "persistCookies" {
+			fmt.Fprintf(&b, "%s=%s\n", key, request.get(key))
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func parseSettings(text string) (global Settings, request *Settings, err error) {
+	request = &Settings{}
+	var section *Settings
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "[global]":
+			section = &global
+			continue
+		case line == "[request]":
+			section = request
+			continue
+		case section == nil:
+			return global, nil, fmt.Errorf("line %d: expected [global] or [request]", i+1)
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return global, nil, fmt.Errorf("line %d: expected key=value", i+1)
+		}
+		if err := section.set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return global, nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+
+	if err := global.Validate(); err != nil {
+		return global, nil, fmt.Errorf("[global]: %w", err)
+	}
+	if err := request.Validate(); err != nil {
+		return global, nil, fmt.Errorf("[request]: %w", err)
+	}
+	if request.PersistCookies != nil {
+		return global, nil, errors.New("[request]: persistCookies can only be set globally")
+	}
+	if request.isEmpty() {
+		request = nil
+	}
+
+	return global, request, nil
+}
+
+// newClient builds the http client for the effective settings of a request,
+// with a transport from transports so connections are reused between requests.
+func newClient(s Settings, transports *transportCache) (*http.Client, error) {
+	s = defaultSettings().merge(&s)
+
+	timeout, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeout: %w", err)
+	}
+
+	transport, err := transports.transport(s)
+	if err != nil {
+		return nil, err
+	}
+
+	follow, maxRedirects := *s.FollowRedirects, *s.MaxRedirects
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !follow {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// transportCache keeps one transport per proxy and TLS settings for the
+// session, a nil cache builds a new transport for every client.
+type transportCache struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func (c *transportCache) transport(s Settings) (*http.Transport, error) {
+	if c == nil {
+		return newTransport(s)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join([]string{s.Proxy, strconv.FormatBool(*s.Insecure), s.CACert, s.ClientCert, s.ClientKey}, "\n")
+	if transport, ok := c.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := newTransport(s)
+	if err != nil {
+		return nil, err
+	}
+	if c.transports == nil {
+		c.transports = map[string]*http.Transport{}
+	}
+	c.transports[key] = transport
+
+	return transport, nil
+}
+
+// newTransport builds a transport for the proxy and TLS settings of s.
+func newTransport(s Settings) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.Proxy != "" {
+		proxy, err := url.Parse(s.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: *s.Insecure}
+	if s.CACert != "" {
+		pem, err := os.ReadFile(expandPath(s.CACert))
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", s.CACert)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if s.ClientCert != "" {
+		key := s.ClientKey
+		if key == "" {
+			key = s.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(expandPath(s.ClientCert), expandPath(key))
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return transport, nil
+}
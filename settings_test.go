@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+)
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantGlobal  string
+		wantRequest string
+		wantErr     string
+	}{
+		{
+			name:       "defaults",
+			text:       formatSettings(Settings{}, nil),
+			wantGlobal: formatSettings(Settings{}, nil),
+		},
+		{
+			name:        "both sections",
+			text:        "[global]\ntimeout = 30s\npersistCookies=true\n\n# comment\n[request]\nfollowRedirects=false\nmaxRedirects=3\nproxy=http://localhost:8080",
+			wantGlobal:  "timeout=30s persistCookies=true",
+			wantRequest: "followRedirects=false maxRedirects=3 proxy=http://localhost:8080",
+		},
+		{name: "outside a section", text: "timeout=1s", wantErr: "line 1: expected [global] or [request]"},
+		{name: "no value", text: "[global]\ntimeout", wantErr: "line 2: expected key=value"},
+		{name: "unknown key", text: "[request]\nretries=3", wantErr: `line 2: unknown setting "retries"`},
+		{name: "invalid bool", text: "[global]\ninsecure=maybe", wantErr: "line 2: invalid insecure"},
+		{name: "invalid timeout", text: "[request]\ntimeout=soon", wantErr: "[request]: invalid timeout"},
+		{name: "negative redirects", text: "[global]\nmaxRedirects=-1", wantErr: "maxRedirects must not be negative"},
+		{name: "invalid proxy", text: "[global]\nproxy=localhost", wantErr: "invalid proxy url"},
+		{name: "request cookies", text: "[request]\npersistCookies=true", wantErr: "persistCookies can only be set globally"},
+	}
+
+	// settingsString lists the settings that are set as key=value pairs.
+	settingsString := func(s *Settings) string {
+		if s == nil {
+			return ""
+		}
+		var set []string
+		for _, key := range settingKeys {
+			if value := s.get(key); value != "" {
+				set = append(set, key+"="+value)
+			}
+		}
+		return strings.Join(set, " ")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global, request, err := parseSettings(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseSettings() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSettings() error = %v", err)
+			}
+
+			if tt.name == "defaults" {
+				if !global.isEmpty() || request != nil {
+					t.Errorf("parseSettings() = %+v, %+v, want no settings", global, request)
+				}
+				return
+			}
+			if got := settingsString(&global); got != tt.wantGlobal {
+				t.Errorf("global = %q, want %q", got, tt.wantGlobal)
+			}
+			if got := settingsString(request); got != tt.wantRequest {
+				t.Errorf("request = %q, want %q", got, tt.wantRequest)
+			}
+		})
+	}
+}
+
+func TestSettingsMerge(t *testing.T) {
+	follow, noFollow, three := true, false, 3
+	global := Settings{Timeout: "30s", FollowRedirects: &follow, Proxy: "http://proxy:8080"}
+	merged := global.merge(&Settings{FollowRedirects: &noFollow, MaxRedirects: &three})
+
+	if merged.Timeout != "30s" || *merged.FollowRedirects || *merged.MaxRedirects != 3 || merged.Proxy != "http://proxy:8080" {
+		t.Errorf("merge() = %+v", merged)
+	}
+	if !*global.FollowRedirects {
+		t.Error("merge() changed the global settings")
+	}
+}
+
+func TestNewClientRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte("home"))
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	noFollow, one := false, 1
+	tests := []struct {
+		name     string
+		settings Settings
+		path     string
+		want     int
+	}{
+		{name: "followed by default", path: "/old", want: http.StatusOK},
+		{name: "not followed", settings: Settings{FollowRedirects: &noFollow}, path: "/old", want: http.StatusFound},
+		{name: "within the limit", settings: Settings{MaxRedirects: &one}, path: "/old", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newClient(tt.settings, nil)
+			if err != nil {
+				t.Fatalf("newClient() error = %v", err)
+			}
+			res, err := client.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transports := &transportCache{}
+	get := func(s Settings) (*http.Client, bool) {
+		t.Helper()
+		client, err := newClient(s, transports)
+		if err != nil {
+			t.Fatalf("newClient() error = %v", err)
+		}
+
+		var reused bool
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), "GET", server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		return client, reused
+	}
+
+	insecure, timeout := true, "5s"
+	first, _ := get(Settings{})
+	second, reused := get(Settings{Timeout: timeout})
+	if first.Transport != second.Transport || !reused {
+		t.Errorf("clients with the same transport settings don't share connections, reused = %v", reused)
+	}
+	third, reused := get(Settings{Insecure: &insecure})
+	if third.Transport == first.Transport || reused {
+		t.Errorf("clients with other TLS settings share a transport")
+	}
+}
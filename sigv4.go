@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// signAWSv4 signs req with AWS Signature Version 4. Credentials that are not
+// set in auth are taken from the standard AWS environment variables.
+func signAWSv4(req *http.Request, auth *Auth, now time.Time) error {
+	accessKey := cmp.Or(auth.AccessKey, os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretKey := cmp.Or(auth.SecretKey, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	sessionToken := auth.SessionToken
+	if auth.AccessKey == "" {
+		sessionToken = cmp.Or(sessionToken, os.Getenv("AWS_SESSION_TOKEN"))
+	}
+	if accessKey == "" || secretKey == "" {
+		return errors.New("awsv4 auth requires accessKey and secretKey, or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	var body []byte
+	if req.GetBody != nil {
+		r, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		if body, err = io.ReadAll(r); err != nil {
+			return err
+		}
+	}
+	payloadHash := sha256Hex(body)
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+	if auth.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	headers := map[string]string{"host": cmp.Or(req.Host, req.URL.Host)}
+	for key, values := range req.Header {
+		if strings.EqualFold(key, "Authorization") {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+	names := slices.Sorted(maps.Keys(headers))
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4Path(sentPath(req.URL), auth.Service != "s3"),
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, auth.Region, auth.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{date, auth.Region, auth.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, accessKey, scope, signedHeaders, signature))
+
+	return nil
+}
+
+// sigV4Path returns the canonical uri of the escaped path as it is sent. S3
+// expects it normalized, all other services expect it escaped a second time.
+func sigV4Path(path string, doubleEscape bool) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if doubleEscape {
+			segments[i] = sigV4Escape(segment)
+		} else if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = sigV4Escape(unescaped)
+		}
+	}
+
+	return cmp.Or(strings.Join(segments, "/"), "/")
+}
+
+// sentPath returns the path of u the way it is written in the request
+// line, which an opaque url sets as is.
+func sentPath(u *url.URL) string {
+	if u.Opaque == "" {
+		return u.EscapedPath()
+	}
+	if host, ok := strings.CutPrefix(u.Opaque, "//"); ok {
+		_, path, _ := strings.Cut(host, "/")
+		return "/" + path
+	}
+
+	return u.Opaque
+}
+
+// sigV4Query returns the canonical query string, sorted by key and value.
+func sigV4Query(query url.Values) string {
+	var pairs [][2]string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{sigV4Escape(key), sigV4Escape(value)})
+		}
+	}
+	slices.SortFunc(pairs, func(a, b [2]string) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+
+	return strings.Join(encoded, "&")
+}
+
+// sigV4Escape escapes everything but the RFC 3986 unreserved characters.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The requests and signatures of the AWS Signature Version 4 test suite
+func TestSignAWSv4(t *testing.T) {
+	auth := &Auth{
+		Type:      "awsv4",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		headers       map[string]string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			method:        "GET",
+			path:          "/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "post-x-www-form-urlencoded",
+			method:        "POST",
+			path:          "/",
+			headers:       map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:          "Param1=value1",
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+		{
+			name:          "get-utf8",
+			method:        "GET",
+			path:          "/ሴ",
+			signedHeaders: "host;x-amz-date",
+			signature:     "8318018e0b0f223aa2bbf98705b62bb787dc9c0e678f255a891fd03141be5d85",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com/", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The suite sends the path unescaped
+			req.URL.Opaque = tt.path
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			if err := signAWSv4(req, auth, now); err != nil {
+				t.Fatalf("signAWSv4() error = %v", err)
+			}
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+				tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization = %q, want %q", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+		})
+	}
+}
+
+func TestSigV4Path(t *testing.T) {
+	tests := []struct {
+		url          string
+		doubleEscape bool
+		want         string
+	}{
+		{"https://example.com", true, "/"},
+		{"https://example.com/ሴ", true, "/%25E1%2588%25B4"},
+		{"https://example.com/ሴ", false, "/%E1%88%B4"},
+		{"https://example.com/a%20b/c", true, "/a%2520b/c"},
+		{"https://example.com/a%20b/c", false, "/a%20b/c"},
+		{"https://example.com/a+b/%7e", false, "/a%2Bb/~"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sigV4Path(sentPath(req.URL), tt.doubleEscape); got != tt.want {
+			t.Errorf("sigV4Path(%q, %v) = %q, want %q", tt.url, tt.doubleEscape, got, tt.want)
+		}
+	}
+}
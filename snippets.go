@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type snippetGenerator struct {
+	name     string
+	generate func(req *http.Request, body string) string
+}
+
+var snippetGenerators = []snippetGenerator{
+	{name: "curl", generate: formatCurl},
+	{name: "Go", generate: goSnippet},
+	{name: "Python", generate: pythonSnippet},
+	{name: "JavaScript", generate: javascriptSnippet},
+	{name: "HTTPie", generate: httpieSnippet},
+}
+
+// snippetIndex returns the index of the generator called name.
+func snippetIndex(name string) int {
+	return slices.IndexFunc(snippetGenerators, func(g snippetGenerator) bool {
+		return g.name == name
+	})
+}
+
+// generateSnippet renders req as code in the language of the generator at index.
+func generateSnippet(index int, req *http.Request) (string, error) {
+	body, err := requestBodyString(req)
+	if err != nil {
+		return "", err
+	}
+
+	return snippetGenerators[index].generate(req, body), nil
+}
+
+func requestBodyString(req *http.Request) (string, error) {
+	if req.GetBody == nil {
+		return "", nil
+	}
+
+	reader, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+func eachHeader(header http.Header, fn func(key, value string)) {
+	for _, key := range slices.Sorted(maps.Keys(header)) {
+		for _, value := range header[key] {
+			fn(key, value)
+		}
+	}
+}
+
+func goSnippet(req *http.Request, body string) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if body != "" {
+		b.WriteString("\t\"strings\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n")
+
+	bodyArg := "nil"
+	if body != "" {
+		fmt.Fprintf(&b, "\tbody := strings.NewReader(%s)\n", strconv.Quote(body))
+		bodyArg = "body"
+	}
+	fmt.Fprintf(&b, "\treq, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(req.Method), strconv.Quote(req.URL.String()), bodyArg)
+	b.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+
+	eachHeader(req.Header, func(key, value string) {
+		fmt.Fprintf(&b, "\treq.Header.Add(%s, %s)\n", strconv.Quote(key), strconv.Quote(value))
+	})
+
+	b.WriteString("\n\tres, err := http.DefaultClient.Do(req)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	b.WriteString("\tdefer res.Body.Close()\n\n")
+	b.WriteString("\tresBody, err := io.ReadAll(res.Body)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\n")
+	b.WriteString("\tfmt.Println(res.Status)\n\tfmt.Println(string(resBody))\n}\n")
+
+	return b.String()
+}
+
+func pythonSnippet(req *http.Request, body string) string {
+	var b strings.Builder
+	b.WriteString("import requests\n\n")
+	fmt.Fprintf(&b, "url = %s\n", jsonString(req.URL.String()))
+
+	args := ""
+	if len(req.Header) > 0 {
+		b.WriteString("headers = {\n")
+		for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+			fmt.Fprintf(&b, "    %s: %s,\n", jsonString(key), jsonString(strings.Join(req.Header[key], ", ")))
+		}
+		b.WriteString("}\n")
+		args += ", headers=headers"
+	}
+
+	if body != "" {
+		fmt.Fprintf(&b, "data = %s\n", jsonString(body))
+		args += ", data=data"
+	}
+
+	fmt.Fprintf(&b, "\nresponse = requests.request(%s, url%s)\n\n", jsonString(req.Method), args)
+	b.WriteString("print(response.status_code)\nprint(response.text)\n")
+
+	return b.String()
+}
+
+func javascriptSnippet(req *http.Request, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "const response = await fetch(%s, {\n", jsonString(req.URL.String()))
+	fmt.Fprintf(&b, "  method: %s,\n", jsonString(req.Method))
+
+	if len(req.Header) > 0 {
+		b.WriteString("  headers: {\n")
+		for _, key := range slices.Sorted(maps.Keys(req.Header)) {
+			fmt.Fprintf(&b, "    %s: %s,\n", jsonString(key), jsonString(strings.Join(req.Header[key], ", ")))
+		}
+		b.WriteString("  },\n")
+	}
+
+	if body != "" {
+		fmt.Fprintf(&b, "  body: %s,\n", jsonString(body))
+	}
+
+	b.WriteString("});\n\nconsole.log(response.status);\nconsole.log(await response.text());\n")
+
+	return b.String()
+}
+
+func httpieSnippet(req *http.Request, body string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "http %s %s", req.Method, shellQuote(req.URL.String()))
+
+	eachHeader(req.Header, func(key, value string) {
+		fmt.Fprintf(&b, " \\\n  %s", shellQuote(key+":"+value))
+	})
+
+	if body != "" {
+		fmt.Fprintf(&b, " \\\n  --raw %s", shellQuote(body))
+	}
+
+	return b.String()
+}
+
+// jsonString quotes s as a JSON string literal, which is also valid in
+// Python and JavaScript.
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return strconv.Quote(s)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSnippet(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://example.com/users", strings.NewReader(`{"name":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	for i, g := range snippetGenerators {
+		if got := snippetIndex(g.name); got != i {
+			t.Errorf("snippetIndex(%q) = %d, want %d", g.name, got, i)
+		}
+		snippet, err := generateSnippet(i, req)
+		if err != nil {
+			t.Fatalf("generateSnippet(%s) error = %v", g.name, err)
+		}
+		if !strings.Contains(snippet, "https://example.com/users") {
+			t.Errorf("%s snippet = %q, want the url", g.name, snippet)
+		}
+	}
+
+	if snippetIndex("curl") < 0 {
+		t.Fatal("no curl snippet to copy requests as")
+	}
+	if got := snippetIndex("COBOL"); got != -1 {
+		t.Errorf("snippetIndex() of a missing generator = %d, want -1", got)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version 1 stored the untyped collection map, version 2 the Collection type.
+const collectionSchemaVersion = 2
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+type collectionFile struct {
+	Version    int             `json:"version"`
+	Collection json.RawMessage `json:"collection"`
+}
+
+// configDir returns the postui config directory, honoring $XDG_CONFIG_HOME.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "postui"), nil
+}
+
+func collectionsDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "collections"), nil
+}
+
+func collectionPath(name string) (string, error) {
+	dir, err := collectionsDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, slug(name)+".json"), nil
+}
+
+func listCollectionFiles() ([]string, error) {
+	dir, err := collectionsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func loadCollection(path string) (*Collection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file collectionFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	if file.Version < 1 || file.Version > collectionSchemaVersion {
+		return nil, fmt.Errorf("%s: unsupported collection schema version %d", filepath.Base(path), file.Version)
+	}
+
+	if len(file.Collection) == 0 || string(file.Collection) == "null" {
+		return &Collection{}, nil
+	}
+
+	if file.Version == 1 {
+		var legacy map[string]any
+		if err := json.Unmarshal(file.Collection, &legacy); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+
+		return migrateLegacyCollection(legacy), nil
+	}
+
+	collection, err := parseCollection(file.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return collection, nil
+}
+
+func saveCollection(path string, collection *Collection) error {
+	collectionJson, err := json.Marshal(collection)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(collectionFile{
+		Version:    collectionSchemaVersion,
+		Collection: collectionJson,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so a crash never leaves a half written file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	defer func() {
+		// No-op once the rename succeeded
+		_ = os.Remove(tmpName)
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, path)
+}
+
+// availablePath returns path, or path with a numeric suffix if a file with
+// that name already exists.
+func availablePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	candidate := path
+	for i := 2; ; i++ {
+		if _, err := os.Stat(candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// isAvailablePath reports whether file is path, or path with a numeric suffix
+// added by availablePath.
+func isAvailablePath(file, path string) bool {
+	if file == path {
+		return true
+	}
+
+	ext := filepath.Ext(path)
+	suffix, found := strings.CutPrefix(file, strings.TrimSuffix(path, ext)+"-")
+	if !found || !strings.HasSuffix(suffix, ext) {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(suffix, ext))
+
+	return err == nil && n >= 2
+}
+
+func slug(name string) string {
+	s := nonSlugChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	s = strings.Trim(s, "-")
+	if s == "" {
+		return "default"
+	}
+
+	return s
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"My API":        "my-api",
+		"my-api":        "my-api",
+		"  Users / v2 ": "users-v2",
+		"snake_case":    "snake_case",
+		"ünïcode":       "n-code",
+		"!!!":           "default",
+		"":              "default",
+	}
+	for name, want := range tests {
+		if got := slug(name); got != want {
+			t.Errorf("slug(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAvailablePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "my-api.json")
+	if got := availablePath(path); got != path {
+		t.Errorf("availablePath() = %q, want %q", got, path)
+	}
+
+	for _, name := range []string{"my-api.json", "my-api-2.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := availablePath(path), filepath.Join(dir, "my-api-3.json"); got != want {
+		t.Errorf("availablePath() = %q, want %q", got, want)
+	}
+}
+
+func TestIsAvailablePath(t *testing.T) {
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"/c/my-api.json", true},
+		{"/c/my-api-2.json", true},
+		{"/c/my-api-12.json", true},
+		{"/c/my-api-1.json", false},
+		{"/c/my-api-v2.json", false},
+		{"/c/my-api-2.yaml", false},
+		{"/c/other.json", false},
+		{"/d/my-api-2.json", false},
+	}
+	for _, tt := range tests {
+		if got := isAvailablePath(tt.file, "/c/my-api.json"); got != tt.want {
+			t.Errorf("isAvailablePath(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
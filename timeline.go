@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+const timelineBarWidth = 30
+
+// timeline collects the httptrace timings of a request and each redirect hop.
+type timeline struct {
+	mu   sync.Mutex
+	hops []*timelineHop
+
+	start     time.Time
+	headersAt time.Time
+	end       time.Time
+}
+
+type timelineHop struct {
+	method     string
+	url        string
+	statusCode int
+	location   string
+	reused     bool
+
+	start, dnsStart, dnsDone, connectStart, connectDone time.Time
+	tlsStart, tlsDone, gotConn, wroteRequest, firstByte time.Time
+}
+
+// phase is a named span of time within a hop.
+type phase struct {
+	name       string
+	start, end time.Time
+}
+
+func newTimeline(req *http.Request) *timeline {
+	t := &timeline{start: time.Now()}
+	t.hops = append(t.hops, &timelineHop{method: req.Method, url: req.URL.String(), start: t.start})
+
+	return t
+}
+
+func (t *timeline) hop() *timelineHop {
+	return t.hops[len(t.hops)-1]
+}
+
+func (t *timeline) record(f func(h *timelineHop)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(t.hop())
+}
+
+func (t *timeline) trace() *httptrace.ClientTrace {
+	now := time.Now
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func(h *timelineHop) {
+				h.reused = info.Reused
+				h.gotConn = now()
+			})
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.record(func(h *timelineHop) { h.dnsStart = now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func(h *timelineHop) { h.dnsDone = now() })
+		},
+		ConnectStart: func(string, string) {
+			t.record(func(h *timelineHop) {
+				// Only the first of several dialed addresses counts
+				if h.connectStart.IsZero() {
+					h.connectStart = now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.record(func(h *timelineHop) {
+				if err == nil {
+					h.connectDone = now()
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			t.record(func(h *timelineHop) { h.tlsStart = now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func(h *timelineHop) { h.tlsDone = now() })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.record(func(h *timelineHop) { h.wroteRequest = now() })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func(h *timelineHop) { h.firstByte = now() })
+		},
+	}
+}
+
+// redirect is called from CheckRedirect before the client follows a redirect
+// response to req.
+func (t *timeline) redirect(req *http.Request) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if req.Response != nil {
+		t.hop().statusCode = req.Response.StatusCode
+		t.hop().location = req.Response.Header.Get("Location")
+	}
+	t.hops = append(t.hops, &timelineHop{method: req.Method, url: req.URL.String(), start: time.Now()})
+}
+
+// finish records the final response, whose headers arrived at headersAt and
+// whose body was read completely at end. It is a redirect when the client
+// doesn't follow redirects.
+func (t *timeline) finish(res *http.Response, headersAt, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hop().statusCode = res.StatusCode
+	if res.StatusCode/100 == 3 {
+		t.hop().location = res.Header.Get("Location")
+	}
+	t.headersAt = headersAt
+	t.end = end
+}
+
+func (h *timelineHop) phases() []phase {
+	var phases []phase
+	add := func(name string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			phases = append(phases, phase{name, start, end})
+		}
+	}
+
+	add("DNS lookup", h.dnsStart, h.dnsDone)
+	add("TCP connect", h.connectStart, h.connectDone)
+	add("TLS handshake", h.tlsStart, h.tlsDone)
+	add("Request sent", h.gotConn, h.wroteRequest)
+	add("Waiting (TTFB)", h.wroteRequest, h.firstByte)
+
+	return phases
+}
+
+func (t *timeline) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.end.Sub(t.start)
+	if total <= 0 {
+		return ""
+	}
+
+	// Bars are drawn on a shared time axis to show where the time went
+	bar := func(start, end time.Time) string {
+		offset := int(int64(timelineBarWidth) * int64(start.Sub(t.start)) / int64(total))
+		width := max(int(int64(timelineBarWidth)*int64(end.Sub(start))/int64(total)), 1)
+		offset = min(offset, timelineBarWidth-width)
+		return strings.Repeat(" ", offset) + strings.Repeat("█", width)
+	}
+
+	var b strings.Builder
+	for i, h := range t.hops {
+		status := ""
+		if h.statusCode > 0 {
+			status = fmt.Sprintf("  %d %s", h.statusCode, http.StatusText(h.statusCode))
+		}
+		fmt.Fprintf(&b, "%s %s%s\n", h.method, h.url, status)
+		if h.location != "" {
+			fmt.Fprintf(&b, "  redirected to %s\n", h.location)
+		}
+		if h.reused {
+			b.WriteString("  reused connection\n")
+		}
+
+		for _, p := range h.phases() {
+			fmt.Fprintf(&b, "  %-17s %9s  %s\n", p.name, formatDuration(p.end.Sub(p.start)), bar(p.start, p.end))
+		}
+		if i == len(t.hops)-1 && !t.headersAt.IsZero() {
+			fmt.Fprintf(&b, "  %-17s %9s  %s\n", "Content download", formatDuration(t.end.Sub(t.headersAt)), bar(t.headersAt, t.end))
+		}
+		b.WriteRune('\n')
+	}
+
+	redirects := ""
+	switch len(t.hops) {
+	case 1:
+	case 2:
+		redirects = " (1 redirect)"
+	default:
+		redirects = fmt.Sprintf(" (%d redirects)", len(t.hops)-1)
+	}
+	fmt.Fprintf(&b, "Total %s%s", formatDuration(total), redirects)
+
+	return b.String()
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return fmt.Sprintf("%d µs", d.Microseconds())
+	}
+
+	return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimelineString(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	tl := &timeline{
+		start:     start,
+		headersAt: at(80),
+		end:       at(100),
+		hops: []*timelineHop{
+			{
+				method: "GET", url: "http://example.com/old", statusCode: 301, location: "https://example.com/new",
+				start: start, dnsStart: at(0), dnsDone: at(10), connectStart: at(10), connectDone: at(20),
+				gotConn: at(20), wroteRequest: at(20), firstByte: at(30),
+			},
+			{
+				method: "GET", url: "https://example.com/new", statusCode: 200,
+				start: at(30), connectStart: at(30), connectDone: at(40), tlsStart: at(40), tlsDone: at(60),
+				gotConn: at(60), wroteRequest: at(60).Add(500 * time.Microsecond), firstByte: at(80),
+			},
+		},
+	}
+
+	want := `GET http://example.com/old  301 Moved Permanently
+  redirected to https://example.com/new
+  DNS lookup          10.0 ms  ███
+  TCP connect         10.0 ms     ███
+  Request sent           0 µs        █
+  Waiting (TTFB)      10.0 ms        ███
+
+GET https://example.com/new  200 OK
+  TCP connect         10.0 ms           ███
+  TLS handshake       20.0 ms              ██████
+  Request sent         500 µs                    █
+  Waiting (TTFB)      19.5 ms                    █████
+  Content download    20.0 ms                          ██████
+
+Total 100.0 ms (1 redirect)`
+	if got := tl.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := (&timeline{start: start, end: start}).String(); got != "" {
+		t.Errorf("String() of an unfinished timeline = %q", got)
+	}
+}
+
+func TestTimelineRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	insecure, follow := true, false
+	send := func(s Settings) responseMsg {
+		t.Helper()
+		msg := doRequest(context.Background(), 1, requestSpec{method: "GET", url: server.URL + "/old", settings: s}, nil, nil, &transportCache{})()
+		res, ok := msg.(responseMsg)
+		if !ok {
+			t.Fatalf("doRequest() = %v, want a response", msg)
+		}
+		return res
+	}
+
+	res := send(Settings{Insecure: &insecure})
+	hops := regexp.MustCompile(`(?m)^GET \S+.*$`).FindAllString(res.timeline, -1)
+	wantHops := []string{
+		"GET " + server.URL + "/old  302 Found",
+		"GET " + server.URL + "/moved  301 Moved Permanently",
+		"GET " + server.URL + "/new  200 OK",
+	}
+	if strings.Join(hops, "\n") != strings.Join(wantHops, "\n") {
+		t.Errorf("hops = %q, want %q", hops, wantHops)
+	}
+
+	for _, want := range []string{"redirected to /moved", "redirected to /new", "TCP connect", "TLS handshake", "Waiting (TTFB)", "reused connection", "(2 redirects)"} {
+		if !strings.Contains(res.timeline, want) {
+			t.Errorf("timeline doesn't show %q:\n%s", want, res.timeline)
+		}
+	}
+	// The first hop dials, the next ones reuse its connection
+	if first, _, _ := strings.Cut(res.timeline, "\n\n"); !strings.Contains(first, "TLS handshake") || strings.Contains(first, "reused connection") {
+		t.Errorf("first hop:\n%s", first)
+	}
+	if strings.Count(res.timeline, "Content download") != 1 || !strings.Contains(res.timeline[strings.LastIndex(res.timeline, "GET "):], "Content download") {
+		t.Errorf("content download isn't shown for the last hop only:\n%s", res.timeline)
+	}
+
+	res = send(Settings{Insecure: &insecure, FollowRedirects: &follow})
+	if res.statusCode != http.StatusFound || strings.Contains(res.timeline, "redirects)") || !strings.Contains(res.timeline, "302 Found\n  redirected to /moved") {
+		t.Errorf("timeline without following redirects:\n%s", res.timeline)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// secretMask is shown instead of secret values in the environment editor.
+const secretMask = "********"
+
+var errVaultLocked = errors.New("the secret vault is locked")
+
+// Vault holds the secret variable values of all environments, stored as an
+// age passphrase encrypted file.
+type Vault struct {
+	path       string
+	passphrase string
+	// Environment name to variable name to value
+	secrets map[string]map[string]string
+}
+
+func vaultPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "vault.age"), nil
+}
+
+// openVault decrypts the vault at path, or starts an empty one protected by
+// passphrase if the file does not exist yet.
+func openVault(path, passphrase string) (*Vault, error) {
+	v := &Vault{path: path, passphrase: passphrase, secrets: map[string]map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if errors.Is(err, age.ErrIncorrectIdentity) {
+		return nil, errors.New("wrong vault passphrase")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	if err := json.Unmarshal(plaintext, &v.secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	return v, nil
+}
+
+func (v *Vault) save() error {
+	recipient, err := age.NewScryptRecipient(v.passphrase)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	w, err := age.Encrypt(&b, recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(v.path, b.Bytes(), 0o600)
+}
+
+func (v *Vault) get(env, name string) (string, bool) {
+	value, ok := v.secrets[env][name]
+	return value, ok
+}
+
+// values returns the secrets of env that are named in names.
+func (v *Vault) values(env string, names []string) map[string]string {
+	values := map[string]string{}
+	for _, name := range names {
+		if value, ok := v.get(env, name); ok {
+			values[name] = value
+		}
+	}
+
+	return values
+}
+
+// setAll replaces the secrets of env, and reports whether anything changed.
+func (v *Vault) setAll(env string, secrets map[string]string) bool {
+	current := v.secrets[env]
+	changed := len(current) != len(secrets)
+	for name, value := range secrets {
+		if existing, ok := current[name]; !ok || existing != value {
+			changed = true
+		}
+	}
+
+	if len(secrets) == 0 {
+		delete(v.secrets, env)
+	} else {
+		v.secrets[env] = secrets
+	}
+
+	return changed
+}
+
+// mask replaces the cleartext secret values of env in s with their {{name}}
+// placeholder.
+func (v *Vault) mask(env, s string) string {
+	secrets := v.secrets[env]
+	names := slices.SortedFunc(maps.Keys(secrets), func(a, b string) int {
+		// Longer values first so a secret containing another is masked whole
+		return cmp.Or(len(secrets[b])-len(secrets[a]), strings.Compare(a, b))
+	})
+
+	var oldnew []string
+	for _, name := range names {
+		// Very short values would mask unrelated text
+		if len(secrets[name]) < 4 {
+			continue
+		}
+		oldnew = append(oldnew, secrets[name], "{{"+name+"}}")
+	}
+	if len(oldnew) == 0 {
+		return s
+	}
+
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
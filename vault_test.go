@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultMask(t *testing.T) {
+	v := &Vault{secrets: map[string]map[string]string{
+		"dev": {
+			"TOKEN":  "abcd",
+			"BEARER": "Bearer abcdef",
+			"KEY":    "abcdef",
+			"PIN":    "123",
+			"SAME_A": "duplicate",
+			"SAME_B": "duplicate",
+		},
+	}}
+
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Authorization: Bearer abcdef", "Authorization: {{BEARER}}"},
+		{"key=abcdef&token=abcd", "key={{KEY}}&token={{TOKEN}}"},
+		{"abcdefabcd", "{{KEY}}{{TOKEN}}"},
+		{"pin 123", "pin 123"},
+		{"duplicate", "{{SAME_A}}"},
+	}
+	for _, tt := range tests {
+		// Repeated since map order changes between runs
+		for range 20 {
+			if got := v.mask("dev", tt.s); got != tt.want {
+				t.Fatalf("mask(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		}
+	}
+
+	if got := v.mask("prod", "abcdef"); got != "abcdef" {
+		t.Errorf("mask() of another environment = %q", got)
+	}
+}
+
+func TestVaultMaskError(t *testing.T) {
+	v := &Vault{secrets: map[string]map[string]string{"dev": {"API_KEY": "s3cr3t-key"}}}
+	err := &url.Error{Op: "Get", URL: "https://example.com/?key=s3cr3t-key", Err: errors.New("connection refused")}
+
+	if got, want := v.mask("dev", err.Error()), `Get "https://example.com/?key={{API_KEY}}": connection refused`; got != want {
+		t.Errorf("mask() = %q, want %q", got, want)
+	}
+}
+
+func TestVaultSaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.age")
+	v, err := openVault(path, "passphrase")
+	if err != nil {
+		t.Fatalf("openVault() of a new vault error = %v", err)
+	}
+	if !v.setAll("dev", map[string]string{"TOKEN": "abcd"}) {
+		t.Fatal("setAll() reported no change")
+	}
+	if err := v.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	opened, err := openVault(path, "passphrase")
+	if err != nil {
+		t.Fatalf("openVault() error = %v", err)
+	}
+	if value, ok := opened.get("dev", "TOKEN"); !ok || value != "abcd" {
+		t.Errorf("get() = %q, %v, want abcd", value, ok)
+	}
+
+	if _, err := openVault(path, "wrong"); err == nil {
+		t.Error("openVault() with a wrong passphrase succeeded")
+	}
+}